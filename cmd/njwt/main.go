@@ -0,0 +1,39 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package main contains the njwt command line tool, used to generate, decode and verify tokens.
+package main
+
+import (
+	"os"
+
+	"github.com/napptive/njwt/internal/cli"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Version of the command line tool, set at build time.
+var Version = "local"
+
+// Commit from which the command line tool has been built, set at build time.
+var Commit string
+
+func main() {
+	// The library logs are only shown if something goes wrong, as the results are written to the standard output.
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	os.Exit(cli.New(Version, Commit, os.Stdin, os.Stdout, os.Stderr).Run(os.Args[1:]))
+}
@@ -0,0 +1,276 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cli contains the commands of the njwt command line tool, used to generate, decode and verify
+// tokens while debugging authentication issues.
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+)
+
+const (
+	// HumanOutput prints the results in a human readable form.
+	HumanOutput = "human"
+	// JSONOutput prints the results as JSON documents.
+	JSONOutput = "json"
+	// SecretEnvVar with the environment variable read if no secret is passed as a flag, so that the secret
+	// is not stored in the shell history.
+	SecretEnvVar = "NJWT_SECRET"
+)
+
+// command with the definition of a subcommand of the tool.
+type command struct {
+	// description with a short explanation of the command.
+	description string
+	// run executes the command with the remaining arguments.
+	run func(c *CLI, args []string) error
+}
+
+// commands with the list of subcommands indexed by name.
+var commands = map[string]command{
+	"generate": {"Generate a token from a personal claim stored in a JSON file", (*CLI).generate},
+	"decode":   {"Decode a token without verifying its signature", (*CLI).decode},
+	"verify":   {"Verify a token with a secret or a public key", (*CLI).verify},
+	"expiry":   {"Report the time remaining until a token expires", (*CLI).expiry},
+	"keygen":   {"Generate a secret or a key pair to sign tokens", (*CLI).keygen},
+	"version":  {"Print the version of the tool", (*CLI).version},
+}
+
+// CLI with the njwt command line tool.
+type CLI struct {
+	// Version of the tool.
+	Version string
+	// Commit with the hash of the commit the tool has been built from.
+	Commit string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// New creates the command line tool reading and writing on the given streams.
+func New(version string, commit string, stdin io.Reader, stdout io.Writer, stderr io.Writer) *CLI {
+	return &CLI{
+		Version: version,
+		Commit:  commit,
+		stdin:   stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+	}
+}
+
+// Run executes the command of the arguments and returns the exit code of the process: 0 on success, 1 if
+// the command fails, for example, verifying an invalid token, and 2 if the arguments are not valid.
+func (c *CLI) Run(args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return 2
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		c.usage()
+		return 0
+	}
+	cmd, exists := commands[args[0]]
+	if !exists {
+		fmt.Fprintf(c.stderr, "unknown command %q\n\n", args[0])
+		c.usage()
+		return 2
+	}
+	err := cmd.run(c, args[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(c.stderr, "Error: %s\n", err.Error())
+		return 1
+	}
+}
+
+// errUsage is returned when the flags of a command are not valid. The flag package has already reported
+// the problem.
+var errUsage = errors.New("invalid usage")
+
+// usage prints the list of commands.
+func (c *CLI) usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(c.stderr, "Usage: njwt <command> [flags]")
+	fmt.Fprintln(c.stderr, "\nCommands:")
+	w := tabwriter.NewWriter(c.stderr, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", name, commands[name].description)
+	}
+	_ = w.Flush()
+	fmt.Fprintln(c.stderr, "\nUse \"njwt <command> -h\" for more information about a command.")
+}
+
+// newFlagSet creates the flag set of a command, including the output format flag.
+func (c *CLI) newFlagSet(name string, usage string, output *string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: njwt %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(output, "output", HumanOutput, "Output format: human or json")
+	return fs
+}
+
+// parseFlags parses the arguments of a command, checking the output format.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return checkOutput(fs, fs.Lookup("output").Value.String())
+}
+
+// checkOutput checks that the output format is supported.
+func checkOutput(fs *flag.FlagSet, output string) error {
+	if output != HumanOutput && output != JSONOutput {
+		fmt.Fprintf(fs.Output(), "invalid output format %q, use %s or %s\n", output, HumanOutput, JSONOutput)
+		return errUsage
+	}
+	return nil
+}
+
+// print writes the result of a command using the selected output format.
+func (c *CLI) print(output string, result interface{}, human func(w io.Writer)) error {
+	if output == JSONOutput {
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return nerrors.NewInternalErrorFrom(err, "cannot encode result")
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	human(w)
+	return w.Flush()
+}
+
+// readInput reads the content of a file, or the standard input if the path is "-".
+func (c *CLI) readInput(path string) ([]byte, error) {
+	if path == "-" {
+		content, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return nil, nerrors.NewInternalErrorFrom(err, "cannot read standard input")
+		}
+		return content, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nerrors.NewInvalidArgumentErrorFrom(err, "cannot read file [%s]", path)
+	}
+	return content, nil
+}
+
+// readToken obtains the token from the positional argument of a command, or from the standard input if it
+// is missing or "-".
+func (c *CLI) readToken(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() > 1 {
+		return "", nerrors.NewInvalidArgumentError("only one token can be passed")
+	}
+	source := fs.Arg(0)
+	if source == "" {
+		source = "-"
+	}
+	token := source
+	if source == "-" {
+		content, err := c.readInput(source)
+		if err != nil {
+			return "", err
+		}
+		token = string(content)
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", nerrors.NewInvalidArgumentError("token must be provided")
+	}
+	return token, nil
+}
+
+// readSecret obtains the secret from the flags, a file or the environment, in that order. An empty secret is
+// returned if none is set.
+func (c *CLI) readSecret(secret string, secretFile string) (string, error) {
+	if secret != "" && secretFile != "" {
+		return "", nerrors.NewInvalidArgumentError("secret and secret-file cannot be used at the same time")
+	}
+	if secretFile != "" {
+		content, err := c.readInput(secretFile)
+		if err != nil {
+			return "", err
+		}
+		secret = strings.TrimSpace(string(content))
+		if secret == "" {
+			return "", nerrors.NewInvalidArgumentError("secret file [%s] is empty", secretFile)
+		}
+	}
+	if secret == "" {
+		secret = os.Getenv(SecretEnvVar)
+	}
+	return secret, nil
+}
+
+// stringList is a flag that can be repeated to build a list of values.
+type stringList []string
+
+// String returns the values separated by commas.
+func (sl *stringList) String() string {
+	return strings.Join(*sl, ",")
+}
+
+// Set adds a new value to the list.
+func (sl *stringList) Set(value string) error {
+	*sl = append(*sl, value)
+	return nil
+}
+
+// version prints the version of the tool.
+func (c *CLI) version(args []string) error {
+	var output string
+	fs := c.newFlagSet("version", "", &output)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	result := map[string]string{"version": c.Version, "commit": c.Commit}
+	return c.print(output, result, func(w io.Writer) {
+		if c.Commit == "" {
+			fmt.Fprintf(w, "njwt %s\n", c.Version)
+			return
+		}
+		fmt.Fprintf(w, "njwt %s (%s)\n", c.Version, c.Commit)
+	})
+}
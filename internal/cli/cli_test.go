@@ -0,0 +1,158 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("njwt command line tool", func() {
+
+	var dir string
+	var claimFile string
+	var stdin *bytes.Buffer
+	var stdout *bytes.Buffer
+	var stderr *bytes.Buffer
+
+	run := func(args ...string) int {
+		stdout.Reset()
+		stderr.Reset()
+		return New("test", "commit", stdin, stdout, stderr).Run(args)
+	}
+
+	generateToken := func(args ...string) string {
+		gomega.Expect(run(append([]string{"generate", "-claim", claimFile}, args...)...)).Should(gomega.Equal(0), stderr.String())
+		return strings.TrimSpace(stdout.String())
+	}
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "cli")
+		gomega.Expect(err).Should(gomega.Succeed())
+		claimFile = filepath.Join(dir, "claim.json")
+		content, err := json.Marshal(njwt.NewAuthxClaim("userID", "username", "accountID", "accountName",
+			"envID", true, "zoneID", "zoneURL", nil))
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(os.WriteFile(claimFile, content, 0600)).To(gomega.Succeed())
+		stdin = &bytes.Buffer{}
+		stdout = &bytes.Buffer{}
+		stderr = &bytes.Buffer{}
+	})
+
+	ginkgo.AfterEach(func() {
+		gomega.Expect(os.RemoveAll(dir)).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should generate tokens that can be recovered with the secret", func() {
+		token := generateToken("-secret", "secret", "-issuer", "authx")
+		claim, err := njwt.New().Recover(token, "secret", &njwt.AuthxClaim{})
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(claim.Issuer).Should(gomega.Equal("authx"))
+		gomega.Expect(claim.GetAuthxClaim().Username).Should(gomega.Equal("username"))
+	})
+
+	ginkgo.It("should decode tokens without the secret", func() {
+		token := generateToken("-secret", "secret")
+		gomega.Expect(run("decode", "-output", "json", token)).Should(gomega.Equal(0), stderr.String())
+		result := make(map[string]interface{})
+		gomega.Expect(json.Unmarshal(stdout.Bytes(), &result)).To(gomega.Succeed())
+		gomega.Expect(result["header"]).Should(gomega.HaveKeyWithValue("alg", "HS256"))
+		gomega.Expect(result["claim"]).Should(gomega.HaveKey("pc"))
+
+		stdin.WriteString(token + "\n")
+		gomega.Expect(run("decode")).Should(gomega.Equal(0), stderr.String())
+		gomega.Expect(stdout.String()).Should(gomega.ContainSubstring("username"))
+	})
+
+	ginkgo.It("should verify tokens with the secret", func() {
+		refreshFile := filepath.Join(dir, "refresh.json")
+		gomega.Expect(os.WriteFile(refreshFile, []byte(`{"UserID":"userID","TokenID":"tokenID"}`), 0600)).To(gomega.Succeed())
+		gomega.Expect(run("generate", "-claim", refreshFile, "-type", RefreshClaimType)).Should(gomega.Equal(1))
+		gomega.Expect(stderr.String()).Should(gomega.ContainSubstring("secret or a key"))
+		gomega.Expect(run("generate", "-claim", claimFile, "-secret", "secret", "-type", RefreshClaimType)).Should(gomega.Equal(1))
+		gomega.Expect(stderr.String()).Should(gomega.ContainSubstring("unknown field"))
+		stdin.WriteString(`{"UserID":"userID","TokenID":"tokenID"}`)
+		gomega.Expect(run("generate", "-claim", "-", "-secret", "secret", "-type", RefreshClaimType)).Should(gomega.Equal(0), stderr.String())
+		token := strings.TrimSpace(stdout.String())
+		gomega.Expect(run("verify", "-secret", "secret", "-type", RefreshClaimType, token)).Should(gomega.Equal(0), stderr.String())
+		gomega.Expect(stdout.String()).Should(gomega.ContainSubstring("true"))
+		gomega.Expect(run("verify", "-secret", "other", "-type", RefreshClaimType, token)).Should(gomega.Equal(1))
+		gomega.Expect(stderr.String()).Should(gomega.ContainSubstring("signature is invalid"))
+		gomega.Expect(run("verify", "-secret", "secret", "-issuer", "other", token)).Should(gomega.Equal(1))
+	})
+
+	ginkgo.It("should sign and verify tokens with generated keys", func() {
+		for _, alg := range []string{"RS256", "ES384", "EdDSA"} {
+			keyFile := filepath.Join(dir, alg)
+			gomega.Expect(run("keygen", "-alg", alg, "-out", keyFile)).Should(gomega.Equal(0), stderr.String())
+			token := generateToken("-key", keyFile, "-kid", alg)
+			gomega.Expect(run("verify", "-key", keyFile+".pub", token)).Should(gomega.Equal(0), stderr.String())
+			gomega.Expect(stdout.String()).Should(gomega.ContainSubstring(alg))
+		}
+	})
+
+	ginkgo.It("should generate HMAC secrets", func() {
+		gomega.Expect(run("keygen", "-output", "json")).Should(gomega.Equal(0), stderr.String())
+		result := keygenResult{}
+		gomega.Expect(json.Unmarshal(stdout.Bytes(), &result)).To(gomega.Succeed())
+		gomega.Expect(result.Algorithm).Should(gomega.Equal("HS256"))
+		gomega.Expect(result.Secret).ShouldNot(gomega.BeEmpty())
+		gomega.Expect(result.PrivateKey).Should(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should report the time to expiry", func() {
+		token := generateToken("-secret", "secret", "-expiration", "30s")
+		gomega.Expect(run("expiry", "-output", "json", token)).Should(gomega.Equal(0), stderr.String())
+		result := expiryResult{}
+		gomega.Expect(json.Unmarshal(stdout.Bytes(), &result)).To(gomega.Succeed())
+		gomega.Expect(result.Expired).Should(gomega.BeTrue())
+		gomega.Expect(result.ExpiresIn).Should(gomega.BeNumerically("~", 30, 2))
+
+		gomega.Expect(run("expiry", "-margin", "0s", token)).Should(gomega.Equal(0), stderr.String())
+		gomega.Expect(stdout.String()).Should(gomega.ContainSubstring("false"))
+	})
+
+	ginkgo.It("should reject the tokens without a numeric expiration", func() {
+		unsignedToken := func(payload string) string {
+			encode := base64.RawURLEncoding.EncodeToString
+			return encode([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + encode([]byte(payload)) + ".signature"
+		}
+		gomega.Expect(run("expiry", unsignedToken(`{"exp":"soon"}`))).Should(gomega.Equal(1))
+		gomega.Expect(stderr.String()).Should(gomega.ContainSubstring("must be a number"))
+		gomega.Expect(run("expiry", unsignedToken(`{"sub":"user"}`))).Should(gomega.Equal(1))
+		gomega.Expect(stderr.String()).Should(gomega.ContainSubstring("do not contain expiration"))
+	})
+
+	ginkgo.It("should reject invalid arguments", func() {
+		gomega.Expect(run()).Should(gomega.Equal(2))
+		gomega.Expect(run("unknown")).Should(gomega.Equal(2))
+		gomega.Expect(run("decode", "-output", "yaml", "token")).Should(gomega.Equal(2))
+		gomega.Expect(run("generate", "-claim", claimFile)).Should(gomega.Equal(1))
+		gomega.Expect(run("generate", "-claim", claimFile, "-secret", "secret", "-type", "other")).Should(gomega.Equal(1))
+		gomega.Expect(run("decode", "not-a-token")).Should(gomega.Equal(1))
+		gomega.Expect(run("help")).Should(gomega.Equal(0))
+	})
+})
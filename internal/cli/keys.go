@@ -0,0 +1,269 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+)
+
+// DefaultRSABits with the size of the generated RSA keys.
+const DefaultRSABits = 2048
+
+// readPEM reads the first PEM block of a file.
+func readPEM(path string) (*pem.Block, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nerrors.NewInvalidArgumentErrorFrom(err, "cannot read key file [%s]", path)
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, nerrors.NewInvalidArgumentError("key file [%s] does not contain a PEM block", path)
+	}
+	return block, nil
+}
+
+// parsePrivateKey parses a private key in PKCS #8, PKCS #1 or SEC 1 form.
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nerrors.NewInvalidArgumentError("unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, nerrors.NewInvalidArgumentError("cannot parse %s block as a private key", block.Type)
+}
+
+// parsePublicKey parses a public key in PKIX or PKCS #1 form, or a certificate. Private keys are also accepted,
+// using their public part.
+func parsePublicKey(block *pem.Block) (crypto.PublicKey, error) {
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if certificate, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return certificate.PublicKey, nil
+	}
+	if signer, err := parsePrivateKey(block); err == nil {
+		return signer.Public(), nil
+	}
+	return nil, nerrors.NewInvalidArgumentError("cannot parse %s block as a public key", block.Type)
+}
+
+// signingMethod returns the signing method with the given name, or the default one for the type of key.
+func signingMethod(alg string, publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	if alg != "" {
+		method := jwt.GetSigningMethod(alg)
+		if method == nil {
+			return nil, nerrors.NewInvalidArgumentError("unsupported signing algorithm %s", alg)
+		}
+		return method, nil
+	}
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			return jwt.SigningMethodES256, nil
+		case 384:
+			return jwt.SigningMethodES384, nil
+		case 521:
+			return jwt.SigningMethodES512, nil
+		}
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, nerrors.NewInvalidArgumentError("cannot infer the signing algorithm of a %T key", publicKey)
+}
+
+// loadSigningKey reads a private key from a PEM file.
+func loadSigningKey(path string, alg string, keyID string) (*njwt.Key, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, err
+	}
+	method, err := signingMethod(alg, privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	return njwt.NewSigningKey(keyID, method, privateKey)
+}
+
+// loadVerificationKey reads a public key from a PEM file.
+func loadVerificationKey(path string, alg string, keyID string) (*njwt.Key, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := parsePublicKey(block)
+	if err != nil {
+		return nil, err
+	}
+	method, err := signingMethod(alg, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return njwt.NewVerificationKey(keyID, method, publicKey)
+}
+
+// keygenResult with the generated key material. Secrets are only generated for HMAC algorithms, and key pairs
+// for the rest.
+type keygenResult struct {
+	Algorithm  string `json:"alg"`
+	Secret     string `json:"secret,omitempty"`
+	PrivateKey string `json:"private_key,omitempty"`
+	PublicKey  string `json:"public_key,omitempty"`
+}
+
+// generateKey creates the key material for a signing algorithm.
+func generateKey(alg string, bits int) (*keygenResult, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, nerrors.NewInvalidArgumentError("unsupported signing algorithm %s", alg)
+	}
+	var privateKey crypto.Signer
+	var err error
+	switch m := method.(type) {
+	case *jwt.SigningMethodHMAC:
+		// The secret has as many bytes as the output of the hash function.
+		secret := make([]byte, m.Hash.Size())
+		if _, err := rand.Read(secret); err != nil {
+			return nil, nerrors.NewInternalErrorFrom(err, "cannot generate secret")
+		}
+		return &keygenResult{Algorithm: alg, Secret: base64.RawURLEncoding.EncodeToString(secret)}, nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		privateKey, err = rsa.GenerateKey(rand.Reader, bits)
+	case *jwt.SigningMethodECDSA:
+		var curve elliptic.Curve
+		switch m.CurveBits {
+		case 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		default:
+			curve = elliptic.P521()
+		}
+		privateKey, err = ecdsa.GenerateKey(curve, rand.Reader)
+	case *jwt.SigningMethodEd25519:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, nerrors.NewInvalidArgumentError("unsupported signing algorithm %s", alg)
+	}
+	if err != nil {
+		return nil, nerrors.NewInternalErrorFrom(err, "cannot generate key")
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nerrors.NewInternalErrorFrom(err, "cannot encode private key")
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, nerrors.NewInternalErrorFrom(err, "cannot encode public key")
+	}
+	return &keygenResult{
+		Algorithm:  alg,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+	}, nil
+}
+
+// keygen generates a secret or a key pair to sign tokens.
+func (c *CLI) keygen(args []string) error {
+	var output, alg, out string
+	var bits int
+	fs := c.newFlagSet("keygen", "[flags]", &output)
+	fs.StringVar(&alg, "alg", jwt.SigningMethodHS256.Alg(), "Signing algorithm of the key: HS256, RS256, ES256, EdDSA, ...")
+	fs.IntVar(&bits, "bits", DefaultRSABits, "Size of the RSA keys")
+	fs.StringVar(&out, "out", "", "Write the private key, or the secret, to <out> and the public key to <out>.pub instead of printing them")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	result, err := generateKey(alg, bits)
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		return c.writeKey(output, out, result)
+	}
+	return c.print(output, result, func(w io.Writer) {
+		if result.Secret != "" {
+			fmt.Fprintln(w, result.Secret)
+			return
+		}
+		fmt.Fprint(w, result.PrivateKey)
+		fmt.Fprint(w, result.PublicKey)
+	})
+}
+
+// writeKey stores the generated key material in files, so that the private part is not printed.
+func (c *CLI) writeKey(output string, out string, result *keygenResult) error {
+	files := map[string]string{"alg": result.Algorithm}
+	private := result.Secret
+	if private == "" {
+		private = result.PrivateKey
+	}
+	if err := os.WriteFile(out, []byte(private), 0600); err != nil {
+		return nerrors.NewInternalErrorFrom(err, "cannot write key file [%s]", out)
+	}
+	files["private_key_file"] = out
+	if result.PublicKey != "" {
+		publicFile := out + ".pub"
+		if err := os.WriteFile(publicFile, []byte(result.PublicKey), 0644); err != nil {
+			return nerrors.NewInternalErrorFrom(err, "cannot write key file [%s]", publicFile)
+		}
+		files["public_key_file"] = publicFile
+	}
+	return c.print(output, files, func(w io.Writer) {
+		fmt.Fprintf(w, "Algorithm:\t%s\n", files["alg"])
+		if result.Secret != "" {
+			fmt.Fprintf(w, "Secret:\t%s\n", files["private_key_file"])
+		} else {
+			fmt.Fprintf(w, "Private key:\t%s\n", files["private_key_file"])
+		}
+		if publicFile, exists := files["public_key_file"]; exists {
+			fmt.Fprintf(w, "Public key:\t%s\n", publicFile)
+		}
+	})
+}
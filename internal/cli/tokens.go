@@ -0,0 +1,365 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+)
+
+const (
+	// AuthxClaimType with the type of the personal claim of the access tokens.
+	AuthxClaimType = "authx"
+	// RefreshClaimType with the type of the personal claim of the refresh tokens.
+	RefreshClaimType = "refresh"
+	// SignupClaimType with the type of the personal claim of the signup tokens.
+	SignupClaimType = "signup"
+	// DefaultIssuer with the issuer of the generated tokens if none is set.
+	DefaultIssuer = "njwt"
+)
+
+// newPersonalClaim returns an empty personal claim of the given type.
+func newPersonalClaim(claimType string) (interface{}, error) {
+	switch claimType {
+	case AuthxClaimType:
+		return &njwt.AuthxClaim{}, nil
+	case RefreshClaimType:
+		return &njwt.RefreshClaim{}, nil
+	case SignupClaimType:
+		return &njwt.SignupClaim{}, nil
+	default:
+		return nil, nerrors.NewInvalidArgumentError("invalid claim type %q, use %s, %s or %s", claimType, AuthxClaimType, RefreshClaimType, SignupClaimType)
+	}
+}
+
+// tokenResult with the information of a token printed by the commands.
+type tokenResult struct {
+	Header map[string]interface{} `json:"header"`
+	Claim  *njwt.Claim            `json:"claim"`
+	Valid  *bool                  `json:"valid,omitempty"`
+}
+
+// printToken prints the header and the claim of a token.
+func (c *CLI) printToken(output string, result tokenResult) error {
+	return c.print(output, result, func(w io.Writer) {
+		if result.Valid != nil {
+			fmt.Fprintf(w, "Valid:\t%t\n", *result.Valid)
+		}
+		for _, key := range sortedKeys(result.Header) {
+			fmt.Fprintf(w, "Header %s:\t%v\n", key, result.Header[key])
+		}
+		claim := result.Claim
+		fmt.Fprintf(w, "Token ID:\t%s\n", claim.Id)
+		fmt.Fprintf(w, "Issuer:\t%s\n", claim.Issuer)
+		if claim.Audience != "" {
+			fmt.Fprintf(w, "Audience:\t%s\n", claim.Audience)
+		}
+		if claim.Subject != "" {
+			fmt.Fprintf(w, "Subject:\t%s\n", claim.Subject)
+		}
+		fmt.Fprintf(w, "Issued at:\t%s\n", formatUnix(claim.IssuedAt))
+		fmt.Fprintf(w, "Not before:\t%s\n", formatUnix(claim.NotBefore))
+		fmt.Fprintf(w, "Expires at:\t%s\n", formatUnix(claim.ExpiresAt))
+		fields, err := toMap(claim.PersonalClaim)
+		if err != nil {
+			fmt.Fprintf(w, "Personal claim:\t%v\n", claim.PersonalClaim)
+			return
+		}
+		for _, key := range sortedKeys(fields) {
+			value := fields[key]
+			if raw, err := json.Marshal(value); err == nil && !isScalar(value) {
+				value = string(raw)
+			}
+			fmt.Fprintf(w, "%s:\t%v\n", key, value)
+		}
+	})
+}
+
+// formatUnix formats a timestamp of a claim.
+func formatUnix(timestamp int64) string {
+	if timestamp == 0 {
+		return "-"
+	}
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
+
+// toMap transforms a personal claim into a map of fields.
+func toMap(value interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// isScalar checks if a value decoded from JSON is not an array or an object.
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		return false
+	default:
+		return true
+	}
+}
+
+// sortedKeys returns the keys of a map in alphabetical order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// tokenHeader returns the header of a token without verifying it.
+func tokenHeader(token string) (map[string]interface{}, error) {
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return nil, nerrors.NewInvalidArgumentErrorFrom(err, "cannot parse token")
+	}
+	return parsed.Header, nil
+}
+
+// generate creates a new token from a personal claim.
+func (c *CLI) generate(args []string) error {
+	var output, claimType, claimFile, issuer, audience, secret, secretFile, keyFile, alg, keyID string
+	var expiration time.Duration
+	fs := c.newFlagSet("generate", "-claim <file> [flags]", &output)
+	fs.StringVar(&claimType, "type", AuthxClaimType, "Type of the personal claim: authx, refresh or signup")
+	fs.StringVar(&claimFile, "claim", "", "JSON file with the personal claim, or - to read it from the standard input")
+	fs.StringVar(&issuer, "issuer", DefaultIssuer, "Issuer of the token")
+	fs.StringVar(&audience, "audience", "", "Audience of the token")
+	fs.DurationVar(&expiration, "expiration", time.Hour, "Time until the token expires")
+	fs.StringVar(&secret, "secret", "", "Secret used to sign the token with HS256, defaults to the "+SecretEnvVar+" environment variable")
+	fs.StringVar(&secretFile, "secret-file", "", "File with the secret used to sign the token")
+	fs.StringVar(&keyFile, "key", "", "PEM file with the private key used to sign the token")
+	fs.StringVar(&alg, "alg", "", "Signing algorithm used with the key, inferred from the key if empty")
+	fs.StringVar(&keyID, "kid", "", "Identifier of the key stamped in the kid header")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if claimFile == "" {
+		return nerrors.NewInvalidArgumentError("claim file must be provided")
+	}
+	pc, err := newPersonalClaim(claimType)
+	if err != nil {
+		return err
+	}
+	content, err := c.readInput(claimFile)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(pc); err != nil {
+		return nerrors.NewInvalidArgumentErrorFrom(err, "cannot decode %s claim", claimType)
+	}
+	claim := njwt.NewClaim(issuer, expiration, pc)
+	if audience != "" {
+		claim.WithAudience(audience)
+	}
+
+	var token *string
+	if keyFile != "" {
+		if secret != "" || secretFile != "" {
+			return nerrors.NewInvalidArgumentError("a secret and a key cannot be used at the same time")
+		}
+		key, err := loadSigningKey(keyFile, alg, keyID)
+		if err != nil {
+			return err
+		}
+		tokenMgr, err := njwt.NewWithKey(key)
+		if err != nil {
+			return err
+		}
+		if token, err = tokenMgr.Generate(claim); err != nil {
+			return nerrors.NewInternalErrorFrom(err, "cannot generate token")
+		}
+	} else {
+		if secret, err = c.readSecret(secret, secretFile); err != nil {
+			return err
+		}
+		if secret == "" {
+			return nerrors.NewInvalidArgumentError("a secret or a key must be provided")
+		}
+		if token, err = njwt.New().Generate(claim, secret); err != nil {
+			return nerrors.NewInternalErrorFrom(err, "cannot generate token")
+		}
+	}
+	return c.print(output, map[string]string{"token": *token}, func(w io.Writer) {
+		fmt.Fprintln(w, *token)
+	})
+}
+
+// decode prints the content of a token without verifying its signature.
+func (c *CLI) decode(args []string) error {
+	var output, claimType string
+	fs := c.newFlagSet("decode", "[flags] [token]", &output)
+	fs.StringVar(&claimType, "type", AuthxClaimType, "Type of the personal claim: authx, refresh or signup")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	token, err := c.readToken(fs)
+	if err != nil {
+		return err
+	}
+	pc, err := newPersonalClaim(claimType)
+	if err != nil {
+		return err
+	}
+	header, err := tokenHeader(token)
+	if err != nil {
+		return err
+	}
+	claim, err := njwt.New().RecoverUnverified(token, pc)
+	if err != nil {
+		return nerrors.NewInvalidArgumentErrorFrom(err, "cannot decode token")
+	}
+	return c.printToken(output, tokenResult{Header: header, Claim: claim})
+}
+
+// verify checks the signature and the standard claims of a token.
+func (c *CLI) verify(args []string) error {
+	var output, claimType, secret, secretFile, keyFile, alg string
+	var issuers, audiences stringList
+	var leeway time.Duration
+	fs := c.newFlagSet("verify", "[flags] [token]", &output)
+	fs.StringVar(&claimType, "type", AuthxClaimType, "Type of the personal claim: authx, refresh or signup")
+	fs.StringVar(&secret, "secret", "", "Secret used to verify tokens signed with HMAC, defaults to the "+SecretEnvVar+" environment variable")
+	fs.StringVar(&secretFile, "secret-file", "", "File with the secret used to verify the token")
+	fs.StringVar(&keyFile, "key", "", "PEM file with the public key, or the private key, used to verify the token")
+	fs.StringVar(&alg, "alg", "", "Signing algorithm expected with the key, inferred from the key if empty")
+	fs.Var(&issuers, "issuer", "Accepted issuer, can be repeated")
+	fs.Var(&audiences, "audience", "Accepted audience, can be repeated")
+	fs.DurationVar(&leeway, "leeway", 0, "Clock skew tolerated when checking the time claims")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	token, err := c.readToken(fs)
+	if err != nil {
+		return err
+	}
+	pc, err := newPersonalClaim(claimType)
+	if err != nil {
+		return err
+	}
+	header, err := tokenHeader(token)
+	if err != nil {
+		return err
+	}
+	validation := &njwt.ValidationOptions{Issuers: issuers, Audiences: audiences, Leeway: leeway}
+
+	var claim *njwt.Claim
+	if keyFile != "" {
+		if secret != "" || secretFile != "" {
+			return nerrors.NewInvalidArgumentError("a secret and a key cannot be used at the same time")
+		}
+		// The key is used whatever the kid of the token is.
+		keyID, _ := header[njwt.KeyIDHeader].(string)
+		key, err := loadVerificationKey(keyFile, alg, keyID)
+		if err != nil {
+			return err
+		}
+		tokenMgr, err := njwt.NewWithKey(key)
+		if err != nil {
+			return err
+		}
+		claim, err = tokenMgr.Recover(token, pc, validation)
+		if err != nil {
+			return nerrors.NewUnauthenticatedErrorFrom(err, "invalid token")
+		}
+	} else {
+		if secret, err = c.readSecret(secret, secretFile); err != nil {
+			return err
+		}
+		if secret == "" {
+			return nerrors.NewInvalidArgumentError("a secret or a key must be provided")
+		}
+		claim, err = njwt.New().Recover(token, secret, pc, validation)
+		if err != nil {
+			return nerrors.NewUnauthenticatedErrorFrom(err, "invalid token")
+		}
+	}
+	valid := true
+	return c.printToken(output, tokenResult{Header: header, Claim: claim, Valid: &valid})
+}
+
+// expiryResult with the expiration information of a token.
+type expiryResult struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	// ExpiresIn with the number of seconds until the token expires, negative if it has already expired.
+	ExpiresIn int64 `json:"expires_in"`
+	// Expired is true if the token has expired applying the margin.
+	Expired bool `json:"expired"`
+	// Margin with the number of seconds subtracted to the expiration time.
+	Margin int64 `json:"margin"`
+}
+
+// expiry reports the time remaining until a token expires. The signature is not verified.
+func (c *CLI) expiry(args []string) error {
+	var output string
+	var margin time.Duration
+	fs := c.newFlagSet("expiry", "[flags] [token]", &output)
+	fs.DurationVar(&margin, "margin", njwt.DefaultExpirationMargin, "Margin subtracted to the expiration time")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	token, err := c.readToken(fs)
+	if err != nil {
+		return err
+	}
+	expired, err := njwt.IsTokenExpired(token, margin)
+	if err != nil {
+		return nerrors.NewInvalidArgumentErrorFrom(err, "cannot check the expiration of the token")
+	}
+	claim, err := njwt.New().RecoverUnverified(token, nil)
+	if err != nil {
+		return nerrors.NewInvalidArgumentErrorFrom(err, "cannot decode token")
+	}
+	expiresAt := time.Unix(claim.ExpiresAt, 0).UTC()
+	remaining := time.Until(expiresAt).Round(time.Second)
+	result := expiryResult{
+		ExpiresAt: expiresAt,
+		ExpiresIn: int64(remaining / time.Second),
+		Expired:   *expired,
+		Margin:    int64(margin / time.Second),
+	}
+	return c.print(output, result, func(w io.Writer) {
+		fmt.Fprintf(w, "Expires at:\t%s\n", expiresAt.Format(time.RFC3339))
+		if remaining > 0 {
+			fmt.Fprintf(w, "Expires in:\t%s\n", remaining)
+		} else {
+			fmt.Fprintf(w, "Expired:\t%s ago\n", -remaining)
+		}
+		fmt.Fprintf(w, "Expired (margin %s):\t%t\n", margin, *expired)
+	})
+}
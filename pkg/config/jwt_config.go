@@ -29,6 +29,12 @@ type JWTConfig struct {
 	Secret string
 	// Header with the metadata field where the token is stored
 	Header string
+	// Headers with additional metadata fields checked, in order, if the token is not found in Header.
+	Headers []string
+	// Scheme with the optional authentication scheme that prefixes the token, for example, "Bearer". If set,
+	// the prefix is removed from the received tokens and added to the tokens sent by the clients. Received
+	// tokens prefixed with "Bearer" are accepted if it is not set.
+	Scheme string
 }
 
 // NewJWTConfig creates a config object with a given secret and header.
@@ -47,11 +53,44 @@ func (jc JWTConfig) IsValid() error {
 	if jc.Header == "" {
 		return nerrors.NewInvalidArgumentError("header must be filled")
 	}
+	for _, header := range jc.Headers {
+		if header == "" {
+			return nerrors.NewInvalidArgumentError("additional headers cannot be empty")
+		}
+	}
+	if strings.ContainsAny(jc.Scheme, " \t") {
+		return nerrors.NewInvalidArgumentError("scheme cannot contain spaces")
+	}
 	return nil
 }
 
+// TokenHeaders returns the ordered list of metadata fields that may contain the token, in lowercase as
+// stored in the gRPC metadata.
+func (jc JWTConfig) TokenHeaders() []string {
+	headers := make([]string, 0, len(jc.Headers)+1)
+	for _, header := range append([]string{jc.Header}, jc.Headers...) {
+		header = strings.ToLower(header)
+		duplicated := false
+		for _, existing := range headers {
+			duplicated = duplicated || existing == header
+		}
+		if !duplicated {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// HeaderValue returns the value of the header that carries a token, including the scheme if set.
+func (jc JWTConfig) HeaderValue(token string) string {
+	if jc.Scheme == "" {
+		return token
+	}
+	return jc.Scheme + " " + token
+}
+
 // Print the configuration using the application logger.
 func (jc JWTConfig) Print() {
-	log.Info().Str("header", jc.Header).
+	log.Info().Str("header", jc.Header).Strs("headers", jc.Headers).Str("scheme", jc.Scheme).
 		Str("secret", strings.Repeat("*", len(jc.Secret))).Msg("Authorization")
 }
@@ -42,7 +42,19 @@ const (
 	// OriginalUsernameKey with the key that will be injected in the context metadata for signup claims corresponding with the orignal username in the target provider
 	OriginalUsernameKey = "original_username"
 	// IdentityProviderKey with the key that will be injected in the context metadata for signup claims corresponding with the the target provider
-	IdentityProviderKey   = "identity_provider"
+	IdentityProviderKey = "identity_provider"
+	// EnvironmentAccountKey with the name of the key that will be injected in the context metadata corresponding to the environment account identifier.
 	EnvironmentAccountKey = "environment_account_id"
-	AccountsKey           = "accounts"
+	// AccountsKey with the name of the key that will be injected in the context metadata corresponding to the accounts of the user.
+	AccountsKey = "accounts"
 )
+
+// ReservedKeys returns the list of keys that the interceptors inject in the context metadata with the
+// information of a verified authx claim. Clients must not be able to send these keys, as downstream services
+// trust their values. Generic keys such as IDKey are not included, so that clients can still use them.
+func ReservedKeys() []string {
+	return []string{
+		JWTID, JWTIssuedAt, UserIDKey, UsernameKey, AccountIDKey, AccountNameKey, EnvironmentIDKey,
+		AccountAdminKey, ZoneIDKey, ZoneURLKey, EnvironmentAccountKey, AccountsKey,
+	}
+}
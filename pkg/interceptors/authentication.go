@@ -0,0 +1,127 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/config"
+	"github.com/napptive/njwt/pkg/njwt"
+	"google.golang.org/grpc/metadata"
+)
+
+// publicCallContextKey is the key used to mark the context of the calls to public methods.
+type publicCallContextKey struct{}
+
+// isPublicCall checks if the context belongs to a call to a public method.
+func isPublicCall(ctx context.Context) bool {
+	public, _ := ctx.Value(publicCallContextKey{}).(bool)
+	return public
+}
+
+// authorizeFunc checks the token of the incoming context and returns the verified claim.
+type authorizeFunc func(ctx context.Context) (*njwt.Claim, error)
+
+// authenticate verifies the token of a call to the given method, returning the context to be passed to the handler.
+// Public methods are called without verifying the token, unless the optional authentication is enabled and the
+// call contains a token. Refresh tokens are always rejected.
+func authenticate(ctx context.Context, fullMethod string, config config.JWTConfig, opts *options, authorize authorizeFunc) (context.Context, error) {
+	if opts.isPublicMethod(fullMethod) {
+		ctx = context.WithValue(ctx, publicCallContextKey{}, true)
+		if !(opts.optionalAuthentication && hasToken(ctx, config)) {
+			return publicContext(ctx, opts)
+		}
+	}
+	claim, err := authorize(ctx)
+	if err != nil {
+		return nil, nerrors.FromError(err).ToGRPC()
+	}
+	// Refresh tokens are only accepted by the session manager.
+	if claim.Audience == njwt.RefreshTokenAudience {
+		return nil, nerrors.NewUnauthenticatedError("refresh tokens cannot be used as access tokens").ToGRPC()
+	}
+	// add the claim information to the context
+	newCtx, err := newAuthenticatedContext(ctx, claim, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.sessionEpochs != nil {
+		if err := CheckSessionEpoch(newCtx, opts.sessionEpochs); err != nil {
+			return nil, nerrors.FromError(err).ToGRPC()
+		}
+	}
+	return newCtx, nil
+}
+
+// CheckSessionEpoch checks that the token of an authenticated context was not issued before the session epoch
+// of its user. The information is obtained with GetClaimFromContext, so services that only receive the
+// metadata injected by the interceptors (jwt_issued_at) can apply the same check.
+func CheckSessionEpoch(ctx context.Context, store njwt.SessionEpochStore) error {
+	claim, err := GetClaimFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err := njwt.CheckSessionEpoch(store, claim.UserID, claim.IssuedAt); err != nil {
+		return toAuthenticationError(err)
+	}
+	return nil
+}
+
+// isPublicMethod checks if a method can be called without a token.
+func (o *options) isPublicMethod(fullMethod string) bool {
+	if o.publicMethods[fullMethod] {
+		return true
+	}
+	for _, prefix := range o.publicMethodPrefixes {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasToken checks if the incoming context contains a token.
+func hasToken(ctx context.Context, config config.JWTConfig) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, header := range config.TokenHeaders() {
+		for _, value := range md[header] {
+			if strings.TrimSpace(value) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// publicContext returns the context passed to the public methods called without a token. The reserved keys
+// are removed so that handlers cannot mistake the values sent by the client for verified ones.
+func publicContext(ctx context.Context, opts *options) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	clientMD, err := removeReservedKeys(md, opts)
+	if err != nil {
+		return nil, nerrors.FromError(err).ToGRPC()
+	}
+	return metadata.NewIncomingContext(ctx, clientMD), nil
+}
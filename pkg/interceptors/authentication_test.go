@@ -0,0 +1,129 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/helper"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var _ = ginkgo.Describe("Public methods", func() {
+
+	const healthCheck = "/grpc.health.v1.Health/Check"
+	const reflection = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+	const private = "/ping.PingService/Ping"
+
+	config := GetTestJWTConfig()
+	var handlerCtx context.Context
+	var interceptor grpc.UnaryServerInterceptor
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerCtx = ctx
+		return nil, nil
+	}
+	call := func(ctx context.Context, method string) error {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	ginkgo.BeforeEach(func() {
+		handlerCtx = nil
+		interceptor = JwtInterceptor(config,
+			WithPublicMethods(healthCheck),
+			WithPublicMethodPrefixes("/grpc.reflection.v1alpha.ServerReflection/"))
+	})
+
+	ginkgo.It("should call public methods without a token", func() {
+		md := metadata.Pairs(helper.UserIDKey, "attacker")
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		gomega.Expect(call(ctx, healthCheck)).Should(gomega.Succeed())
+		_, ok := ClaimFromContext(handlerCtx)
+		gomega.Expect(ok).Should(gomega.BeFalse())
+		handlerMD, _ := metadata.FromIncomingContext(handlerCtx)
+		gomega.Expect(handlerMD.Get(helper.UserIDKey)).Should(gomega.BeEmpty())
+
+		gomega.Expect(call(context.Background(), reflection)).Should(gomega.Succeed())
+
+		err := call(ctx, private)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should verify the token sent to a public method with optional authentication", func() {
+		interceptor = JwtInterceptor(config, WithPublicMethods(healthCheck), WithOptionalAuthentication())
+
+		authClaim := GetTestAuthxClaim()
+		token, err := njwt.New().Generate(njwt.NewClaim("authx", time.Hour, authClaim), config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+		gomega.Expect(call(ctx, healthCheck)).Should(gomega.Succeed())
+		recovered, err := AuthxClaimFromContext(handlerCtx)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(recovered.UserID).Should(gomega.Equal(authClaim.UserID))
+
+		gomega.Expect(call(context.Background(), healthCheck)).Should(gomega.Succeed())
+
+		invalidCtx, invalidCancel := CreateTestIncomingContext(config.Header, "invalid")
+		defer invalidCancel()
+		gomega.Expect(call(invalidCtx, healthCheck)).ShouldNot(gomega.Succeed())
+	})
+})
+
+var _ = ginkgo.Describe("Session epoch", func() {
+
+	config := GetTestJWTConfig()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	ginkgo.It("should reject the tokens issued before the session epoch of the user", func() {
+		store := njwt.NewMemorySessionEpochStore()
+		interceptor := JwtInterceptor(config, WithSessionEpochStore(store))
+		info := &grpc.UnaryServerInfo{FullMethod: "/ping.PingService/Ping"}
+
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim("authx", time.Hour, authClaim)
+		claim.IssuedAt = time.Now().Add(-time.Minute).Unix()
+		token, err := njwt.New().Generate(claim, config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+
+		_, err = interceptor(ctx, nil, info, handler)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		gomega.Expect(store.SetEpoch(authClaim.UserID, time.Now().Add(-time.Second))).Should(gomega.Succeed())
+		_, err = interceptor(ctx, nil, info, handler)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+
+		newToken, err := njwt.New().Generate(njwt.NewClaim("authx", time.Hour, authClaim), config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+		newCtx, newCancel := CreateTestIncomingContext(config.Header, *newToken)
+		defer newCancel()
+		_, err = interceptor(newCtx, nil, info, handler)
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+})
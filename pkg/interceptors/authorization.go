@@ -0,0 +1,149 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+)
+
+// AccountExtractor returns the name of the account targeted by a request. The request is nil for streams.
+type AccountExtractor func(ctx context.Context, fullMethod string, req interface{}) (string, error)
+
+// CurrentAccountExtractor is an AccountExtractor that returns the current account of the verified claim.
+func CurrentAccountExtractor(ctx context.Context, fullMethod string, req interface{}) (string, error) {
+	authxClaim, err := AuthxClaimFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	accountName, err := authxClaim.GetCurrentAccountName()
+	if err != nil {
+		return "", err
+	}
+	return *accountName, nil
+}
+
+// MethodPolicy with the authorization requirements of a method.
+type MethodPolicy struct {
+	// Roles with the list of roles accepted in the account of the request. If empty, any member of the account is
+	// authorized.
+	Roles []string
+	// Public exempts the method from the authorization checks, including the ones of the default policy.
+	Public bool
+}
+
+// AuthorizationPolicy with the authorization requirements of the methods of a server.
+type AuthorizationPolicy struct {
+	// Methods maps full method names to their policy.
+	Methods map[string]MethodPolicy
+	// Default with the policy applied to the methods that are not included in Methods. If nil, those methods
+	// are not checked. The methods declared public in the JWT interceptors are never checked by the default
+	// policy.
+	Default *MethodPolicy
+	// AccountExtractor with the function that returns the account targeted by a request. If nil, the
+	// CurrentAccountExtractor is used.
+	AccountExtractor AccountExtractor
+}
+
+// policyFor returns the policy of a method, or nil if the method is not protected. The second value indicates
+// if the policy has been defined explicitly for the method.
+func (ap *AuthorizationPolicy) policyFor(fullMethod string) (*MethodPolicy, bool) {
+	if policy, exists := ap.Methods[fullMethod]; exists {
+		return &policy, true
+	}
+	return ap.Default, false
+}
+
+// authorize checks if the verified claim of the context satisfies the policy of a method.
+func (ap *AuthorizationPolicy) authorize(ctx context.Context, fullMethod string, req interface{}) error {
+	policy, explicit := ap.policyFor(fullMethod)
+	if policy == nil || policy.Public {
+		return nil
+	}
+	if isPublicCall(ctx) {
+		// Public methods are only checked by their own policy, and only if the caller sent a token.
+		if _, authenticated := ClaimFromContext(ctx); !explicit || !authenticated {
+			return nil
+		}
+	}
+	authxClaim, err := AuthxClaimFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	extractor := ap.AccountExtractor
+	if extractor == nil {
+		extractor = CurrentAccountExtractor
+	}
+	accountName, err := extractor(ctx, fullMethod, req)
+	if err != nil {
+		log.Warn().Err(err).Str("method", fullMethod).Msg("unable to extract the account of the request")
+		return nerrors.NewPermissionDeniedError("unable to determine the account of the request")
+	}
+	if !authxClaim.HasRole(accountName, policy.Roles...) {
+		return nerrors.NewPermissionDeniedError("user %s is not authorized to call %s on account %s", authxClaim.Username, fullMethod, accountName)
+	}
+	return nil
+}
+
+// WithAuthorizationInterceptor creates a gRPC interceptor that checks the authorization policy. It relies on
+// the verified claim stored in the context, so it is chained after the JWT interceptor of the server.
+// Example:
+//
+//	s = grpc.NewServer(
+//		interceptors.WithServerJWTInterceptor(cfg),
+//		interceptors.WithAuthorizationInterceptor(policy))
+func WithAuthorizationInterceptor(policy AuthorizationPolicy) grpc.ServerOption {
+	return grpc.ChainUnaryInterceptor(AuthorizationInterceptor(policy))
+}
+
+// AuthorizationInterceptor checks that the verified claim has one of the roles required by the policy of the
+// method in the account targeted by the request.
+func AuthorizationInterceptor(policy AuthorizationPolicy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if err := policy.authorize(ctx, info.FullMethod, req); err != nil {
+			return nil, nerrors.FromError(err).ToGRPC()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// WithAuthorizationStreamInterceptor creates a gRPC stream interceptor that checks the authorization policy.
+func WithAuthorizationStreamInterceptor(policy AuthorizationPolicy) grpc.ServerOption {
+	return grpc.ChainStreamInterceptor(AuthorizationStreamInterceptor(policy))
+}
+
+// AuthorizationStreamInterceptor checks that the verified claim has one of the roles required by the policy of
+// the method. As the request is not available when the stream is opened, the account extractor receives nil.
+func AuthorizationStreamInterceptor(policy AuthorizationPolicy) grpc.StreamServerInterceptor {
+	return func(srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		if err := policy.authorize(stream.Context(), info.FullMethod, nil); err != nil {
+			return nerrors.FromError(err).ToGRPC()
+		}
+		return handler(srv, stream)
+	}
+}
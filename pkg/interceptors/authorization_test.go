@@ -0,0 +1,114 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+)
+
+var _ = ginkgo.Describe("Authorization interceptor", func() {
+
+	const adminMethod = "/ping.PingService/Delete"
+	const memberMethod = "/ping.PingService/Ping"
+	const openMethod = "/ping.PingService/Health"
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	contextWithRole := func(role string) context.Context {
+		authxClaim := GetTestAuthxClaim()
+		authxClaim.Accounts[0].Role = role
+		return ContextWithClaim(context.Background(), njwt.NewClaim("authx", time.Hour, authxClaim))
+	}
+	call := func(policy AuthorizationPolicy, ctx context.Context, method string, req interface{}) error {
+		_, err := AuthorizationInterceptor(policy)(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+	policy := AuthorizationPolicy{
+		Methods: map[string]MethodPolicy{
+			adminMethod:  {Roles: []string{"Admin"}},
+			memberMethod: {},
+		},
+	}
+
+	ginkgo.It("should authorize the users with the required role", func() {
+		gomega.Expect(call(policy, contextWithRole("Admin"), adminMethod, nil)).Should(gomega.Succeed())
+		gomega.Expect(call(policy, contextWithRole("Member"), memberMethod, nil)).Should(gomega.Succeed())
+		gomega.Expect(call(policy, contextWithRole("Member"), openMethod, nil)).Should(gomega.Succeed())
+	})
+
+	ginkgo.It("should deny the users without the required role", func() {
+		err := call(policy, contextWithRole("Member"), adminMethod, nil)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.PermissionDenied))
+
+		err = call(policy, context.Background(), adminMethod, nil)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should apply the default policy and the account extractor", func() {
+		extractorPolicy := AuthorizationPolicy{
+			Default: &MethodPolicy{Roles: []string{"Admin"}},
+			AccountExtractor: func(ctx context.Context, fullMethod string, req interface{}) (string, error) {
+				if req == nil {
+					return "", nerrors.NewInvalidArgumentError("missing request")
+				}
+				return req.(string), nil
+			},
+		}
+		authxClaim := GetTestAuthxClaim()
+		ctx := ContextWithClaim(context.Background(), njwt.NewClaim("authx", time.Hour, authxClaim))
+		accountName := authxClaim.Accounts[0].Name
+		gomega.Expect(call(extractorPolicy, ctx, openMethod, accountName)).Should(gomega.Succeed())
+
+		err := call(extractorPolicy, ctx, openMethod, "other-account")
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.PermissionDenied))
+		err = call(extractorPolicy, ctx, openMethod, nil)
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.PermissionDenied))
+	})
+
+	ginkgo.It("should not apply the default policy to the public methods", func() {
+		config := GetTestJWTConfig()
+		defaultPolicy := AuthorizationPolicy{
+			Methods: map[string]MethodPolicy{memberMethod: {Public: true}},
+			Default: &MethodPolicy{Roles: []string{"Admin"}},
+		}
+		chain := func(ctx context.Context, method string) error {
+			_, err := JwtInterceptor(config, WithPublicMethods(openMethod))(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					return nil, call(defaultPolicy, ctx, method, req)
+				})
+			return err
+		}
+		anonymous := context.Background()
+		gomega.Expect(chain(anonymous, openMethod)).Should(gomega.Succeed())
+		gomega.Expect(chain(anonymous, adminMethod)).ShouldNot(gomega.Succeed())
+
+		// Methods mapped to a public policy are exempted even for authenticated users without the role.
+		gomega.Expect(call(defaultPolicy, contextWithRole("Member"), memberMethod, nil)).Should(gomega.Succeed())
+		err := call(defaultPolicy, contextWithRole("Member"), adminMethod, nil)
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.PermissionDenied))
+	})
+})
@@ -0,0 +1,82 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"google.golang.org/grpc/metadata"
+)
+
+// claimContextKey is the key used to store the verified claim in the context.
+type claimContextKey struct{}
+
+// ContextWithClaim returns a new context that contains the verified claim.
+func ContextWithClaim(ctx context.Context, claim *njwt.Claim) context.Context {
+	return context.WithValue(ctx, claimContextKey{}, claim)
+}
+
+// ClaimFromContext returns the verified claim stored in the context by the interceptors.
+func ClaimFromContext(ctx context.Context) (*njwt.Claim, bool) {
+	claim, ok := ctx.Value(claimContextKey{}).(*njwt.Claim)
+	return claim, ok && claim != nil
+}
+
+// AuthxClaimFromContext returns the authx section of the verified claim stored in the context by the interceptors.
+// Example:
+//
+//	authxClaim, err := interceptors.AuthxClaimFromContext(ctx)
+//	if err != nil {
+//		return nil, nerrors.FromError(err).ToGRPC()
+//	}
+func AuthxClaimFromContext(ctx context.Context) (*njwt.AuthxClaim, error) {
+	claim, ok := ClaimFromContext(ctx)
+	if !ok {
+		return nil, nerrors.NewUnauthenticatedError("no claim found in context")
+	}
+	authxClaim := claim.GetAuthxClaim()
+	if authxClaim == nil {
+		return nil, nerrors.NewUnauthenticatedError("claim does not contain authx information")
+	}
+	return authxClaim, nil
+}
+
+// newAuthenticatedContext returns a new context with the verified claim. For backward compatibility, the
+// claim information is also injected in the incoming metadata unless it has been disabled. The reserved keys
+// sent by the client are removed or rejected in both cases.
+func newAuthenticatedContext(ctx context.Context, claim *njwt.Claim, opts *options) (context.Context, error) {
+	if opts.injectMetadata {
+		var err error
+		if ctx, err = addClaimToContext(claim, ctx, opts); err != nil {
+			return nil, err
+		}
+		return ContextWithClaim(ctx, claim), nil
+	}
+	if claim.GetAuthxClaim() == nil {
+		return nil, nerrors.NewUnauthenticatedError("token does not contain an authx claim").ToGRPC()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		clientMD, err := removeReservedKeys(md, opts)
+		if err != nil {
+			return nil, nerrors.FromError(err).ToGRPC()
+		}
+		ctx = metadata.NewIncomingContext(ctx, clientMD)
+	}
+	return ContextWithClaim(ctx, claim), nil
+}
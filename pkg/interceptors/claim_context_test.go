@@ -0,0 +1,114 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/helper"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var _ = ginkgo.Describe("Claim in context", func() {
+
+	var token *string
+	var authClaim *njwt.AuthxClaim
+	var handlerCtx context.Context
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		handlerCtx = ctx
+		return nil, nil
+	}
+
+	ginkgo.BeforeEach(func() {
+		authClaim = GetTestAuthxClaim()
+		claim := njwt.NewClaim("authx", time.Hour, authClaim)
+		var err error
+		token, err = njwt.New().Generate(claim, GetTestJWTConfig().Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+		handlerCtx = nil
+	})
+
+	ginkgo.It("should store the verified claim in the context", func() {
+		config := GetTestJWTConfig()
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+
+		_, err := JwtInterceptor(config)(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		claim, ok := ClaimFromContext(handlerCtx)
+		gomega.Expect(ok).Should(gomega.BeTrue())
+		gomega.Expect(claim.Issuer).Should(gomega.Equal("authx"))
+		gomega.Expect(claim.ExpiresAt).ShouldNot(gomega.BeZero())
+		recovered, err := AuthxClaimFromContext(handlerCtx)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(recovered).Should(gomega.Equal(authClaim))
+
+		// The metadata is still available for backward compatibility.
+		md, _ := metadata.FromIncomingContext(handlerCtx)
+		gomega.Expect(md.Get(helper.UserIDKey)).Should(gomega.Equal([]string{authClaim.UserID}))
+	})
+
+	ginkgo.It("should be able to disable the metadata injection", func() {
+		config := GetTestJWTConfig()
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+
+		_, err := JwtInterceptor(config, WithoutMetadataInjection())(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		md, _ := metadata.FromIncomingContext(handlerCtx)
+		gomega.Expect(md.Get(helper.UserIDKey)).Should(gomega.BeEmpty())
+		extended, err := GetClaimFromContext(handlerCtx)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(extended.AuthxClaim).Should(gomega.Equal(*authClaim))
+		gomega.Expect(extended.ExpiresAt).ShouldNot(gomega.BeZero())
+		gomega.Expect(extended.Issuer).Should(gomega.Equal("authx"))
+	})
+
+	ginkgo.It("should remove spoofed reserved keys without metadata injection", func() {
+		config := GetTestJWTConfig()
+		md := metadata.Pairs(config.Header, *token, helper.UserIDKey, "attacker", "other", "value")
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+
+		_, err := JwtInterceptor(config, WithoutMetadataInjection())(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		gomega.Expect(err).Should(gomega.Succeed())
+		handlerMD, _ := metadata.FromIncomingContext(handlerCtx)
+		gomega.Expect(handlerMD.Get(helper.UserIDKey)).Should(gomega.BeEmpty())
+		gomega.Expect(handlerMD.Get("other")).Should(gomega.Equal([]string{"value"}))
+		extended, err := GetClaimFromContext(handlerCtx)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(extended.UserID).Should(gomega.Equal(authClaim.UserID))
+
+		_, err = JwtInterceptor(config, WithoutMetadataInjection(), WithRejectReservedKeys())(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.InvalidArgument))
+	})
+
+	ginkgo.It("should fail if the context does not contain a claim", func() {
+		_, ok := ClaimFromContext(context.Background())
+		gomega.Expect(ok).Should(gomega.BeFalse())
+		_, err := AuthxClaimFromContext(context.Background())
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+	})
+})
@@ -0,0 +1,158 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"strings"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/config"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// WithClientJWTInterceptor creates a gRPC dial option that attaches the token of the source to the outgoing calls.
+func WithClientJWTInterceptor(config config.JWTConfig, source TokenSource) grpc.DialOption {
+	return grpc.WithUnaryInterceptor(ClientJWTInterceptor(config, source))
+}
+
+// ClientJWTInterceptor attaches the token of the source to the outgoing calls. If the server rejects the call
+// as Unauthenticated and the source supports invalidating its token, the call is retried once with a new token.
+func ClientJWTInterceptor(config config.JWTConfig, source TokenSource) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption) error {
+
+		newCtx, err := addTokenToOutgoingContext(ctx, config, source)
+		if err != nil {
+			return err
+		}
+		err = invoker(newCtx, method, req, reply, cc, opts...)
+		if !shouldRetry(err, source) {
+			return err
+		}
+		log.Debug().Str("method", method).Msg("token rejected by the server, retrying with a new token")
+		if newCtx, err = addTokenToOutgoingContext(ctx, config, source); err != nil {
+			return err
+		}
+		return invoker(newCtx, method, req, reply, cc, opts...)
+	}
+}
+
+// WithClientJWTStreamInterceptor creates a gRPC dial option that attaches the token of the source to the outgoing streams.
+func WithClientJWTStreamInterceptor(config config.JWTConfig, source TokenSource) grpc.DialOption {
+	return grpc.WithStreamInterceptor(ClientJWTStreamInterceptor(config, source))
+}
+
+// ClientJWTStreamInterceptor attaches the token of the source to the outgoing streams. The stream creation is
+// retried once with a new token if it fails as Unauthenticated. Notice that most servers report the error on
+// the first message received, in which case the error is returned to the caller.
+func ClientJWTStreamInterceptor(config config.JWTConfig, source TokenSource) grpc.StreamClientInterceptor {
+	return func(ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption) (grpc.ClientStream, error) {
+
+		newCtx, err := addTokenToOutgoingContext(ctx, config, source)
+		if err != nil {
+			return nil, err
+		}
+		stream, err := streamer(newCtx, desc, cc, method, opts...)
+		if !shouldRetry(err, source) {
+			return stream, err
+		}
+		if newCtx, err = addTokenToOutgoingContext(ctx, config, source); err != nil {
+			return nil, err
+		}
+		return streamer(newCtx, desc, cc, method, opts...)
+	}
+}
+
+// addTokenToOutgoingContext returns a new context with the token of the source in the outgoing metadata.
+func addTokenToOutgoingContext(ctx context.Context, config config.JWTConfig, source TokenSource) (context.Context, error) {
+	token, err := source.Token(ctx)
+	if err != nil {
+		return nil, nerrors.NewUnauthenticatedErrorFrom(err, "unable to obtain token").ToGRPC()
+	}
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	} else {
+		md = md.Copy()
+	}
+	md.Set(config.Header, config.HeaderValue(token))
+	return metadata.NewOutgoingContext(ctx, md), nil
+}
+
+// shouldRetry checks if a call must be retried with a new token, invalidating the current one.
+func shouldRetry(err error, source TokenSource) bool {
+	if status.Code(err) != codes.Unauthenticated {
+		return false
+	}
+	inv, ok := source.(invalidator)
+	if !ok {
+		return false
+	}
+	inv.Invalidate()
+	return true
+}
+
+// jwtCredentials implements the credentials.PerRPCCredentials interface to attach the token of a source to
+// every call of a connection.
+type jwtCredentials struct {
+	config     config.JWTConfig
+	source     TokenSource
+	requireTLS bool
+}
+
+// NewJWTCredentials creates a credentials.PerRPCCredentials that attaches the token of the source to every
+// call. Tokens should only be sent over secure connections, so requireTLS must only be disabled for testing
+// or when the transport is secured by other means.
+// Example:
+//
+//	conn, err := grpc.Dial(address, grpc.WithPerRPCCredentials(interceptors.NewJWTCredentials(cfg, source, true)), ...)
+func NewJWTCredentials(config config.JWTConfig, source TokenSource, requireTLS bool) credentials.PerRPCCredentials {
+	return &jwtCredentials{
+		config:     config,
+		source:     source,
+		requireTLS: requireTLS,
+	}
+}
+
+// GetRequestMetadata returns the metadata containing the token.
+func (jc *jwtCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := jc.source.Token(ctx)
+	if err != nil {
+		return nil, nerrors.NewUnauthenticatedErrorFrom(err, "unable to obtain token").ToGRPC()
+	}
+	return map[string]string{strings.ToLower(jc.config.Header): jc.config.HeaderValue(token)}, nil
+}
+
+// RequireTransportSecurity indicates whether the credentials require a secure connection.
+func (jc *jwtCredentials) RequireTransportSecurity() bool {
+	return jc.requireTLS
+}
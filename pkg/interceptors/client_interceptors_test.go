@@ -0,0 +1,148 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"time"
+
+	grpc_ping_go "github.com/napptive/grpc-ping-go"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// countingTokenSource returns a new token on each call, signed with the secrets of the list in order.
+type countingTokenSource struct {
+	calls      int32
+	secrets    []string
+	expiration time.Duration
+}
+
+func (cts *countingTokenSource) Token(ctx context.Context) (string, error) {
+	call := int(atomic.AddInt32(&cts.calls, 1))
+	secret := cts.secrets[len(cts.secrets)-1]
+	if call <= len(cts.secrets) {
+		secret = cts.secrets[call-1]
+	}
+	claim := njwt.NewClaim("authx", cts.expiration, GetTestAuthxClaim())
+	token, err := njwt.New().Generate(claim, secret)
+	if err != nil {
+		return "", err
+	}
+	return *token, nil
+}
+
+var _ = ginkgo.Describe("Client interceptors", func() {
+
+	var s *grpc.Server
+	var lis *bufconn.Listener
+	config := GetTestJWTConfig()
+
+	dial := func(opts ...grpc.DialOption) grpc_ping_go.PingServiceClient {
+		opts = append(opts, grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := grpc.DialContext(context.Background(), "bufnet", opts...)
+		gomega.Expect(err).Should(gomega.Succeed())
+		return grpc_ping_go.NewPingServiceClient(conn)
+	}
+
+	ginkgo.BeforeEach(func() {
+		lis = bufconn.Listen(bufSize)
+		s = grpc.NewServer(WithServerJWTInterceptor(config))
+		grpc_ping_go.RegisterPingServiceServer(s, pingHandler{})
+		go func() {
+			if err := s.Serve(lis); err != nil {
+				log.Fatal().Errs("Server exited with error: %v", []error{err})
+				return
+			}
+		}()
+	})
+
+	ginkgo.AfterEach(func() {
+		s.Stop()
+		lis.Close()
+	})
+
+	ginkgo.It("should attach the token to the outgoing calls", func() {
+		source := &countingTokenSource{secrets: []string{config.Secret}, expiration: time.Hour}
+		client := dial(WithClientJWTInterceptor(config, NewRefreshingTokenSource(source, time.Minute)))
+		for i := 0; i < 3; i++ {
+			_, err := client.Ping(context.Background(), &grpc_ping_go.PingRequest{RequestNumber: 1})
+			gomega.Expect(err).Should(gomega.Succeed())
+		}
+		gomega.Expect(atomic.LoadInt32(&source.calls)).Should(gomega.Equal(int32(1)))
+	})
+
+	ginkgo.It("should refresh the tokens that are about to expire", func() {
+		source := &countingTokenSource{secrets: []string{config.Secret}, expiration: 30 * time.Second}
+		client := dial(WithClientJWTInterceptor(config, NewRefreshingTokenSource(source, time.Minute)))
+		for i := 0; i < 3; i++ {
+			_, err := client.Ping(context.Background(), &grpc_ping_go.PingRequest{RequestNumber: 1})
+			gomega.Expect(err).Should(gomega.Succeed())
+		}
+		gomega.Expect(atomic.LoadInt32(&source.calls)).Should(gomega.Equal(int32(3)))
+	})
+
+	ginkgo.It("should retry once after an Unauthenticated error", func() {
+		source := &countingTokenSource{secrets: []string{"invalid", config.Secret}, expiration: time.Hour}
+		client := dial(WithClientJWTInterceptor(config, NewRefreshingTokenSource(source, time.Minute)))
+		_, err := client.Ping(context.Background(), &grpc_ping_go.PingRequest{RequestNumber: 1})
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(atomic.LoadInt32(&source.calls)).Should(gomega.Equal(int32(2)))
+
+		source = &countingTokenSource{secrets: []string{"invalid"}, expiration: time.Hour}
+		client = dial(WithClientJWTInterceptor(config, NewRefreshingTokenSource(source, time.Minute)))
+		_, err = client.Ping(context.Background(), &grpc_ping_go.PingRequest{RequestNumber: 1})
+		gomega.Expect(status.Code(err)).Should(gomega.Equal(codes.Unauthenticated))
+		gomega.Expect(atomic.LoadInt32(&source.calls)).Should(gomega.Equal(int32(2)))
+	})
+
+	ginkgo.It("should attach the token using per RPC credentials", func() {
+		source := &countingTokenSource{secrets: []string{config.Secret}, expiration: time.Hour}
+		client := dial(grpc.WithPerRPCCredentials(NewJWTCredentials(config, source, false)))
+		_, err := client.Ping(context.Background(), &grpc_ping_go.PingRequest{RequestNumber: 1})
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+})
+
+var _ = ginkgo.Describe("Refreshing token source", func() {
+
+	ginkgo.It("should check the expiration of the cached token with the clock", func() {
+		clock := njwt.NewFakeClock(time.Now())
+		source := &countingTokenSource{secrets: []string{GetTestJWTConfig().Secret}, expiration: 2 * time.Minute}
+		tokenSource := NewRefreshingTokenSourceWithClock(source, time.Minute, clock)
+		for i := 0; i < 2; i++ {
+			_, err := tokenSource.Token(context.Background())
+			gomega.Expect(err).Should(gomega.Succeed())
+		}
+		gomega.Expect(atomic.LoadInt32(&source.calls)).Should(gomega.Equal(int32(1)))
+		clock.Advance(time.Minute + time.Second)
+		_, err := tokenSource.Token(context.Background())
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(atomic.LoadInt32(&source.calls)).Should(gomega.Equal(int32(2)))
+	})
+})
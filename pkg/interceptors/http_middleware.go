@@ -0,0 +1,152 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/config"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/metadata"
+)
+
+// HTTPMiddleware returns a net/http middleware that verifies the token of the requests with the secret of the
+// configuration, or the keyring if provided. The token is read from the headers of the configuration, with or
+// without the Bearer prefix, and optionally from a cookie or a query parameter. The verified claim is stored
+// in the context of the request, and can be retrieved with ClaimFromContext or AuthxClaimFromContext. Public
+// methods are matched against the path of the request.
+// Example:
+//
+//	http.Handle("/api/", interceptors.HTTPMiddleware(cfg, interceptors.WithTokenCookie("token"))(apiHandler))
+func HTTPMiddleware(config config.JWTConfig, opts ...Option) func(http.Handler) http.Handler {
+	interceptorOpts := newOptions(opts...)
+	return httpMiddleware(config, interceptorOpts, func(ctx context.Context) (*njwt.Claim, error) {
+		return authorizeJWTToken(ctx, config, interceptorOpts)
+	})
+}
+
+// ZoneAwareHTTPMiddleware returns a net/http middleware that verifies the token of the requests attending to
+// the zone that issued it. See HTTPMiddleware for the details.
+func ZoneAwareHTTPMiddleware(config config.JWTConfig, secretProvider SecretProvider, opts ...Option) func(http.Handler) http.Handler {
+	interceptorOpts := newOptions(opts...)
+	return httpMiddleware(config, interceptorOpts, func(ctx context.Context) (*njwt.Claim, error) {
+		return authorizeZoneAwareJWTToken(ctx, config, secretProvider, interceptorOpts)
+	})
+}
+
+// httpMiddleware returns a middleware that authenticates the requests with the given function.
+func httpMiddleware(config config.JWTConfig, opts *options, authorize authorizeFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, err := tokenFromRequest(r, config, opts)
+			if err != nil {
+				writeHTTPError(w, err)
+				return
+			}
+			md := metadata.MD{}
+			if token != "" {
+				md.Set(config.Header, token)
+			}
+			ctx, err := authenticate(metadata.NewIncomingContext(r.Context(), md), r.URL.Path, config, opts, authorize)
+			if err != nil && token == "" {
+				writeHTTPChallenge(w, nerrors.FromGRPC(err))
+				return
+			}
+			if err != nil {
+				writeHTTPError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// HTTPAuthorizationMiddleware returns a net/http middleware that checks the authorization policy attending to
+// the path of the request. It must be applied after one of the authentication middlewares. The account
+// extractor of the policy receives the *http.Request.
+// Example:
+//
+//	handler := interceptors.HTTPMiddleware(cfg)(interceptors.HTTPAuthorizationMiddleware(policy)(apiHandler))
+func HTTPAuthorizationMiddleware(policy AuthorizationPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := policy.authorize(r.Context(), r.URL.Path, r); err != nil {
+				writeHTTPError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tokenFromRequest returns the token of a request, or an empty string if the request does not contain a token.
+// The headers are checked first, followed by the cookie and the query parameter if they are enabled.
+func tokenFromRequest(r *http.Request, config config.JWTConfig, opts *options) (string, error) {
+	token, err := tokenFromHeader(r.Header, config)
+	if err == nil {
+		return token, nil
+	}
+	if nerrors.FromError(err).Code != nerrors.NotFound {
+		return "", err
+	}
+	if opts.tokenCookie != "" {
+		if cookie, err := r.Cookie(opts.tokenCookie); err == nil && cookie.Value != "" {
+			return cookie.Value, nil
+		}
+	}
+	if opts.tokenQueryParameter != "" {
+		return r.URL.Query().Get(opts.tokenQueryParameter), nil
+	}
+	return "", nil
+}
+
+// writeHTTPError writes the response of a request that failed the authentication or authorization checks,
+// including the WWW-Authenticate header defined by RFC 6750.
+func writeHTTPError(w http.ResponseWriter, err error) {
+	// the authentication functions return gRPC errors, while the authorization policy returns extended errors
+	nErr, ok := err.(*nerrors.ExtendedError)
+	if !ok {
+		nErr = nerrors.FromGRPC(err)
+	}
+	switch nErr.Code {
+	case nerrors.NotFound:
+		writeHTTPChallenge(w, nErr)
+	case nerrors.Unauthenticated:
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, nErr.Msg))
+		http.Error(w, nErr.Msg, http.StatusUnauthorized)
+	case nerrors.PermissionDenied:
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", error_description=%q`, nErr.Msg))
+		http.Error(w, nErr.Msg, http.StatusForbidden)
+	case nerrors.InvalidArgument:
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+		http.Error(w, nErr.Msg, http.StatusBadRequest)
+	default:
+		log.Error().Err(err).Msg("unable to authenticate request")
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// writeHTTPChallenge writes the response of a request that does not contain a token. As defined by RFC 6750,
+// the challenge does not include an error code in this case.
+func writeHTTPChallenge(w http.ResponseWriter, err *nerrors.ExtendedError) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, err.Msg, http.StatusUnauthorized)
+}
@@ -0,0 +1,112 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("HTTP middleware", func() {
+
+	config := GetTestJWTConfig()
+	var token string
+	var userID string
+	var handler http.Handler
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authxClaim, err := AuthxClaimFromContext(r.Context()); err == nil {
+			w.Header().Set("X-User-Id", authxClaim.UserID)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	ginkgo.BeforeEach(func() {
+		authClaim := GetTestAuthxClaim()
+		userID = authClaim.UserID
+		generated, err := njwt.New().Generate(njwt.NewClaim("authx", time.Hour, authClaim), config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+		token = *generated
+		handler = HTTPMiddleware(config,
+			WithTokenCookie("token"), WithTokenQueryParameter("access_token"), WithPublicMethods("/healthz"))(next)
+	})
+
+	ginkgo.It("should read the token from the header, cookie or query parameter", func() {
+		withBearer := httptest.NewRequest(http.MethodGet, "/api", nil)
+		withBearer.Header.Set(config.Header, "Bearer "+token)
+		withHeader := httptest.NewRequest(http.MethodGet, "/api", nil)
+		withHeader.Header.Set(config.Header, token)
+		withCookie := httptest.NewRequest(http.MethodGet, "/api", nil)
+		withCookie.AddCookie(&http.Cookie{Name: "token", Value: token})
+		withQuery := httptest.NewRequest(http.MethodGet, "/api?access_token="+token, nil)
+
+		for _, r := range []*http.Request{withBearer, withHeader, withCookie, withQuery} {
+			recorder := serve(r)
+			gomega.Expect(recorder.Code).Should(gomega.Equal(http.StatusOK))
+			gomega.Expect(recorder.Header().Get("X-User-Id")).Should(gomega.Equal(userID))
+		}
+	})
+
+	ginkgo.It("should reject the refresh tokens", func() {
+		refreshClaim := njwt.NewClaim("authx", time.Hour, &njwt.RefreshClaim{UserID: userID, TokenID: "tid", FamilyID: "fid"}).
+			WithAudience(njwt.RefreshTokenAudience)
+		refreshToken, err := njwt.New().Generate(refreshClaim, config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		r := httptest.NewRequest(http.MethodGet, "/api", nil)
+		r.Header.Set(config.Header, "Bearer "+*refreshToken)
+		gomega.Expect(serve(r).Code).Should(gomega.Equal(http.StatusUnauthorized))
+	})
+
+	ginkgo.It("should reject the requests without a valid token", func() {
+		recorder := serve(httptest.NewRequest(http.MethodGet, "/api", nil))
+		gomega.Expect(recorder.Code).Should(gomega.Equal(http.StatusUnauthorized))
+		gomega.Expect(recorder.Header().Get("WWW-Authenticate")).Should(gomega.Equal("Bearer"))
+
+		invalid := httptest.NewRequest(http.MethodGet, "/api", nil)
+		invalid.Header.Set(config.Header, "Bearer invalid")
+		recorder = serve(invalid)
+		gomega.Expect(recorder.Code).Should(gomega.Equal(http.StatusUnauthorized))
+		gomega.Expect(recorder.Header().Get("WWW-Authenticate")).Should(gomega.ContainSubstring(`error="invalid_token"`))
+
+		gomega.Expect(serve(httptest.NewRequest(http.MethodGet, "/healthz", nil)).Code).Should(gomega.Equal(http.StatusOK))
+	})
+
+	ginkgo.It("should reject the requests that do not satisfy the authorization policy", func() {
+		policy := AuthorizationPolicy{Methods: map[string]MethodPolicy{"/admin": {Roles: []string{"Owner"}}}}
+		handler = HTTPMiddleware(config)(HTTPAuthorizationMiddleware(policy)(next))
+
+		admin := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		admin.Header.Set(config.Header, token)
+		recorder := serve(admin)
+		gomega.Expect(recorder.Code).Should(gomega.Equal(http.StatusForbidden))
+		gomega.Expect(recorder.Header().Get("WWW-Authenticate")).Should(gomega.ContainSubstring("insufficient_scope"))
+
+		api := httptest.NewRequest(http.MethodGet, "/api", nil)
+		api.Header.Set(config.Header, token)
+		gomega.Expect(serve(api).Code).Should(gomega.Equal(http.StatusOK))
+	})
+})
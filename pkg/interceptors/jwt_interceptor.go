@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/napptive/nerrors/pkg/nerrors"
@@ -31,25 +32,22 @@ import (
 )
 
 // WithServerJWTInterceptor creates a gRPC interceptor that verifies the JWT received is valid
-func WithServerJWTInterceptor(config config.JWTConfig) grpc.ServerOption {
-	return grpc.UnaryInterceptor(JwtInterceptor(config))
+func WithServerJWTInterceptor(config config.JWTConfig, opts ...Option) grpc.ServerOption {
+	return grpc.UnaryInterceptor(JwtInterceptor(config, opts...))
 
 }
 
 // JwtInterceptor verifies the JWT token and adds the claim information in the context
-func JwtInterceptor(config config.JWTConfig) grpc.UnaryServerInterceptor {
+func JwtInterceptor(config config.JWTConfig, opts ...Option) grpc.UnaryServerInterceptor {
+	interceptorOpts := newOptions(opts...)
 	return func(ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler) (interface{}, error) {
 
-		claim, err := authorizeJWTToken(ctx, config)
-		if err != nil {
-			return nil, nerrors.FromError(err).ToGRPC()
-		}
-
-		// add the claim information to the context metadata
-		newCtx, err := AddClaimToContext(claim, ctx)
+		newCtx, err := authenticate(ctx, info.FullMethod, config, interceptorOpts, func(ctx context.Context) (*njwt.Claim, error) {
+			return authorizeJWTToken(ctx, config, interceptorOpts)
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -58,44 +56,66 @@ func JwtInterceptor(config config.JWTConfig) grpc.UnaryServerInterceptor {
 }
 
 // authorizeJWTToken checks the token and returns the authxClaim
-func authorizeJWTToken(ctx context.Context, config config.JWTConfig) (*njwt.Claim, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, nerrors.NewUnauthenticatedError("retrieving metadata failed")
-	}
-
-	token, ok := md[config.Header]
-	if !ok {
-		return nil, nerrors.NewUnauthenticatedError("no auth details supplied")
-	}
-	if token[0] == "" {
-		return nil, nerrors.NewNotFoundError("error getting token. Log in to the platform")
+func authorizeJWTToken(ctx context.Context, config config.JWTConfig, opts *options) (*njwt.Claim, error) {
+	token, err := tokenFromContext(ctx, config)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check the token and get the authx claim
 	var pc njwt.AuthxClaim
 	var claim *njwt.Claim
-	var err error
-	if claim, err = njwt.New().Recover(token[0], config.Secret, &pc); err != nil {
-		castErr, ok := err.(*jwt.ValidationError)
-		if !ok {
-			return nil, nerrors.NewUnauthenticatedError("error recovering token [%s]", err.Error())
-		}
-		switch castErr.Errors {
-		case jwt.ValidationErrorExpired:
-			return nil, nerrors.NewUnauthenticatedError("[%s]. Please, log in to the platform again", err.Error())
-		default:
-			return nil, nerrors.NewUnauthenticatedError("error recovering token [%s]", err.Error())
+	if opts.keyring != nil {
+		if opts.keyedTokenMgr == nil {
+			return nil, nerrors.NewInternalError("cannot verify token")
 		}
+		claim, err = opts.keyedTokenMgr.Recover(token, &pc, opts.validationOptions())
+	} else if opts.secretSource != nil {
+		claim, err = njwt.RecoverWithSecretSource(njwt.New(), token, opts.secretSource, &pc, opts.validationOptions())
+	} else {
+		claim, err = njwt.New().Recover(token, config.Secret, &pc, opts.validationOptions())
+	}
+	if err != nil {
+		return nil, toAuthenticationError(err)
 	}
 
 	return claim, nil
 }
 
-// AddClaimToContext returns new Context joining the claim information
+// toAuthenticationError transforms the error found while recovering a token into an Unauthenticated error. The
+// errors retrieving the verification key because the request was cancelled or the key source is unavailable are
+// returned as they are, as they are not caused by the token.
+func toAuthenticationError(err error) error {
+	castErr, ok := err.(*jwt.ValidationError)
+	if !ok {
+		return nerrors.NewUnauthenticatedError("error recovering token [%s]", err.Error())
+	}
+	if isRetrievalFailure(castErr.Inner) {
+		return castErr.Inner
+	}
+	switch castErr.Errors {
+	case jwt.ValidationErrorExpired:
+		return nerrors.NewUnauthenticatedError("[%s]. Please, log in to the platform again", err.Error())
+	default:
+		return nerrors.NewUnauthenticatedError("error recovering token [%s]", err.Error())
+	}
+}
+
+// AddClaimToContext returns new Context joining the claim information. Any value of the incoming metadata
+// using one of the reserved keys is replaced by the information of the claim.
 func AddClaimToContext(claim *njwt.Claim, ctx context.Context) (context.Context, error) {
+	return addClaimToContext(claim, ctx, newOptions())
+}
+
+// addClaimToContext returns new Context joining the claim information, removing the reserved keys sent
+// by the client.
+func addClaimToContext(claim *njwt.Claim, ctx context.Context, opts *options) (context.Context, error) {
 	// add the claim information to the context metadata
-	authMap := claim.GetAuthxClaim().ToMap()
+	authxClaim := claim.GetAuthxClaim()
+	if authxClaim == nil {
+		return nil, nerrors.NewUnauthenticatedError("token does not contain an authx claim").ToGRPC()
+	}
+	authMap := authxClaim.ToMap()
 	authMap[helper.JWTID] = claim.Id
 	authMap[helper.JWTIssuedAt] = fmt.Sprint(claim.IssuedAt)
 	md := metadata.New(authMap)
@@ -103,15 +123,44 @@ func AddClaimToContext(claim *njwt.Claim, ctx context.Context) (context.Context,
 	if !ok {
 		return nil, nerrors.NewInternalError("error recovering metadata").ToGRPC()
 	}
+	clientMD, err := removeReservedKeys(oldMD, opts)
+	if err != nil {
+		return nil, nerrors.FromError(err).ToGRPC()
+	}
 	// adds the new metadata to the old one
-	fullMD := metadata.Join(oldMD, md)
+	fullMD := metadata.Join(clientMD, md)
 	// and create new context with this one
 	newCtx := metadata.NewIncomingContext(ctx, fullMD)
 	return newCtx, nil
 }
 
-// GetClaimFromContext gets user info from context
+// removeReservedKeys returns a copy of the metadata without the reserved keys, or an error if the
+// reserved keys must be rejected.
+func removeReservedKeys(md metadata.MD, opts *options) (metadata.MD, error) {
+	result := md.Copy()
+	for _, key := range opts.reservedKeys {
+		// metadata keys are always stored in lowercase
+		key = strings.ToLower(key)
+		if _, exists := result[key]; !exists {
+			continue
+		}
+		if opts.rejectReservedKeys {
+			return nil, nerrors.NewInvalidArgumentError("metadata key [%s] is reserved", key)
+		}
+		delete(result, key)
+	}
+	return result, nil
+}
+
+// GetClaimFromContext gets user info from context. The verified claim stored by the interceptors is used if
+// available, otherwise the information is rebuilt from the incoming metadata.
 func GetClaimFromContext(ctx context.Context) (*njwt.ExtendedAuthxClaim, error) {
+	if claim, ok := ClaimFromContext(ctx); ok && claim.GetAuthxClaim() != nil {
+		return &njwt.ExtendedAuthxClaim{
+			StandardClaims: claim.StandardClaims,
+			AuthxClaim:     *claim.GetAuthxClaim(),
+		}, nil
+	}
 
 	// check that the user id and username are in the metadata
 	md, ok := metadata.FromIncomingContext(ctx)
@@ -204,31 +253,20 @@ func GetClaimFromContext(ctx context.Context) (*njwt.ExtendedAuthxClaim, error)
 	}, nil
 }
 
-func WithServerJWTStreamInterceptor(config config.JWTConfig) grpc.ServerOption {
-	return grpc.StreamInterceptor(JwtStreamInterceptor(config))
+func WithServerJWTStreamInterceptor(config config.JWTConfig, opts ...Option) grpc.ServerOption {
+	return grpc.StreamInterceptor(JwtStreamInterceptor(config, opts...))
 }
 
 // JwtStreamInterceptor verifies the JWT token and adds the claim information in the context
-func JwtStreamInterceptor(config config.JWTConfig) grpc.StreamServerInterceptor {
+func JwtStreamInterceptor(config config.JWTConfig, opts ...Option) grpc.StreamServerInterceptor {
+	interceptorOpts := newOptions(opts...)
 	return func(srv interface{},
 		stream grpc.ServerStream,
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler) error {
 
-		ctx := stream.Context()
-		authClaim, err := authorizeJWTToken(ctx, config)
-		if err != nil {
-			return nerrors.FromError(err).ToGRPC()
-		}
-
-		// add the claim information to the context metadata
-		newCtx, err := AddClaimToContext(authClaim, ctx)
-		if err != nil {
-			return err
-		}
-		// uses this new context in the stream wrapper
-		w := newStreamContextWrapper(stream)
-		w.SetContext(newCtx)
-		return handler(srv, w)
+		return serveStream(srv, stream, info, handler, config, interceptorOpts, func(ctx context.Context) (*njwt.Claim, error) {
+			return authorizeJWTToken(ctx, config, interceptorOpts)
+		})
 	}
 }
@@ -86,7 +86,7 @@ var _ = ginkgo.Describe("JWT interceptor", func() {
 		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
 		defer cancel()
 
-		recovered, err := authorizeJWTToken(ctx, config)
+		recovered, err := authorizeJWTToken(ctx, config, newOptions())
 		gomega.Expect(err).Should(gomega.Succeed())
 		gomega.Expect(recovered.GetAuthxClaim().UserID).Should(gomega.Equal(authClaim.UserID))
 		gomega.Expect(recovered.GetAuthxClaim().Username).Should(gomega.Equal(authClaim.Username))
@@ -107,11 +107,211 @@ var _ = ginkgo.Describe("JWT interceptor", func() {
 		// Create a context with the token
 		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
 		defer cancel()
-		_, err = authorizeJWTToken(ctx, config)
+		_, err = authorizeJWTToken(ctx, config, newOptions())
 		gomega.Expect(err).ShouldNot(gomega.Succeed())
 
 	})
 
+	ginkgo.It("check JWT Token is accepted within the leeway", func() {
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim(utils.GetTestUserId(), -30*time.Second, &authClaim)
+		config := GetTestJWTConfig()
+
+		token, err := njwt.New().Generate(claim, config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+		_, err = authorizeJWTToken(ctx, config, newOptions())
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		opts := newOptions(WithValidationOptions(&njwt.ValidationOptions{Leeway: time.Minute}))
+		_, err = authorizeJWTToken(ctx, config, opts)
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+
+	ginkgo.It("check revoked JWT Token is rejected", func() {
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim(utils.GetTestUserId(), time.Hour, &authClaim)
+		config := GetTestJWTConfig()
+
+		token, err := njwt.New().Generate(claim, config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+		store := njwt.NewMemoryRevocationStore()
+		opts := newOptions(WithRevocationStore(store))
+		_, err = authorizeJWTToken(ctx, config, opts)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(njwt.RevokeClaim(store, claim)).Should(gomega.Succeed())
+		_, err = authorizeJWTToken(ctx, config, opts)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("check JWT Token is verified with the secrets of the secret source", func() {
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim(utils.GetTestUserId(), time.Hour, &authClaim)
+		config := GetTestJWTConfig()
+
+		token, err := njwt.New().Generate(claim, "previous")
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+		_, err = authorizeJWTToken(ctx, config, newOptions())
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		_, err = authorizeJWTToken(ctx, config, newOptions(WithSecretSource(rotatedSecretSource{"current", "previous"})))
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = authorizeJWTToken(ctx, config, newOptions(WithSecretSource(njwt.StaticSecretSource("current"))))
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("check JWT Token is validated with the clock of the interceptor", func() {
+		clock := njwt.NewFakeClock(time.Now())
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaimWithClock(clock, utils.GetTestUserId(), time.Hour, &authClaim)
+		config := GetTestJWTConfig()
+
+		token, err := njwt.New().Generate(claim, config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+		opts := newOptions(WithClock(clock))
+		_, err = authorizeJWTToken(ctx, config, opts)
+		gomega.Expect(err).Should(gomega.Succeed())
+		clock.Advance(2 * time.Hour)
+		_, err = authorizeJWTToken(ctx, config, opts)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+		_, err = authorizeJWTToken(ctx, config, newOptions())
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+
+	ginkgo.It("check spoofed reserved keys never reach the handler", func() {
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim(utils.GetTestUserId(), time.Duration(1)*time.Hour, &authClaim)
+		config := GetTestJWTConfig()
+
+		token, err := njwt.New().Generate(claim, config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		md := metadata.Pairs(config.Header, *token, helper.UserIDKey, "attacker", helper.AccountsKey, "[]", "other", "value")
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+
+		var handlerMD metadata.MD
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			handlerMD, _ = metadata.FromIncomingContext(ctx)
+			return nil, nil
+		}
+		_, err = JwtInterceptor(config)(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(handlerMD.Get(helper.UserIDKey)).Should(gomega.Equal([]string{authClaim.UserID}))
+		gomega.Expect(handlerMD.Get(helper.AccountsKey)).Should(gomega.HaveLen(1))
+		gomega.Expect(handlerMD.Get(helper.AccountsKey)[0]).ShouldNot(gomega.Equal("[]"))
+		gomega.Expect(handlerMD.Get("other")).Should(gomega.Equal([]string{"value"}))
+
+		// Generic keys that are never injected by the interceptors are not rejected.
+		withID := metadata.NewIncomingContext(context.Background(), metadata.Pairs(config.Header, *token, helper.IDKey, "value"))
+		_, err = JwtInterceptor(config, WithRejectReservedKeys())(withID, nil, &grpc.UnaryServerInfo{}, handler)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(handlerMD.Get(helper.IDKey)).Should(gomega.Equal([]string{"value"}))
+
+		handlerMD = nil
+		_, err = JwtInterceptor(config, WithRejectReservedKeys())(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.InvalidArgument))
+		gomega.Expect(handlerMD).Should(gomega.BeNil())
+
+		_, err = JwtInterceptor(config, WithReservedKeys("other"))(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(handlerMD.Get("other")).Should(gomega.BeEmpty())
+	})
+
+	ginkgo.It("check the keyring is not listed on each request", func() {
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim(utils.GetTestUserId(), time.Hour, &authClaim)
+		config := GetTestJWTConfig()
+
+		memoryKeyring, err := njwt.NewMemoryKeyring(njwt.NewHMACKey("v1", "secret"))
+		gomega.Expect(err).Should(gomega.Succeed())
+		keyring := &countingKeyring{Keyring: memoryKeyring}
+		tokenMgr, err := njwt.NewWithKeyring(memoryKeyring)
+		gomega.Expect(err).Should(gomega.Succeed())
+		token, err := tokenMgr.Generate(claim)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		opts := newOptions(WithKeyring(keyring))
+		for i := 0; i < 3; i++ {
+			ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+			_, err := authorizeJWTToken(ctx, config, opts)
+			cancel()
+			gomega.Expect(err).Should(gomega.Succeed())
+		}
+		gomega.Expect(keyring.listed).Should(gomega.BeZero())
+	})
+
+	ginkgo.It("check refresh tokens are rejected as access tokens", func() {
+		authClaim := GetTestAuthxClaim()
+		config := GetTestJWTConfig()
+
+		keyring, err := njwt.NewMemoryKeyring(njwt.NewHMACKey("v1", "secret"))
+		gomega.Expect(err).Should(gomega.Succeed())
+		tokenMgr, err := njwt.NewWithKeyring(keyring)
+		gomega.Expect(err).Should(gomega.Succeed())
+		sessionMgr, err := njwt.NewSessionManager(tokenMgr, njwt.NewMemoryRevocationStore(), njwt.SessionConfig{
+			Issuer: "authx", AccessExpiration: time.Hour, RefreshExpiration: 24 * time.Hour})
+		gomega.Expect(err).Should(gomega.Succeed())
+		pair, err := sessionMgr.Issue(authClaim)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		}
+		interceptors := []grpc.UnaryServerInterceptor{
+			JwtInterceptor(config, WithKeyring(keyring)),
+			ZoneAwareJWTInterceptor(config, nil, WithKeyring(keyring)),
+		}
+		for _, interceptor := range interceptors {
+			ctx, cancel := CreateTestIncomingContext(config.Header, pair.AccessToken)
+			_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			cancel()
+			gomega.Expect(err).Should(gomega.Succeed())
+
+			ctx, cancel = CreateTestIncomingContext(config.Header, pair.RefreshToken)
+			_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+			cancel()
+			gomega.Expect(err).ShouldNot(gomega.Succeed())
+			gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+		}
+	})
+
+	ginkgo.It("check JWT Token is verified with the keyring", func() {
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim(utils.GetTestUserId(), time.Duration(1)*time.Hour, &authClaim)
+		config := GetTestJWTConfig()
+
+		keyring, err := njwt.NewMemoryKeyring(njwt.NewHMACKey("v1", "oldSecret"))
+		gomega.Expect(err).Should(gomega.Succeed())
+		tokenMgr, err := njwt.NewWithKeyring(keyring)
+		gomega.Expect(err).Should(gomega.Succeed())
+		oldToken, err := tokenMgr.Generate(claim)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(keyring.Rotate(njwt.NewHMACKey("v2", "newSecret"))).Should(gomega.Succeed())
+		newToken, err := tokenMgr.Generate(claim)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		for _, token := range []*string{oldToken, newToken} {
+			ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+			recovered, err := authorizeJWTToken(ctx, config, newOptions(WithKeyring(keyring)))
+			cancel()
+			gomega.Expect(err).Should(gomega.Succeed())
+			gomega.Expect(recovered.GetAuthxClaim().UserID).Should(gomega.Equal(authClaim.UserID))
+		}
+	})
+
 })
 
 const (
@@ -224,3 +424,25 @@ var _ = ginkgo.Context("Server interceptor", func() {
 	})
 
 })
+
+// rotatedSecretSource is a njwt.SecretSource with the current secret followed by the previous ones.
+type rotatedSecretSource []string
+
+func (rss rotatedSecretSource) Secret() string {
+	return rss[0]
+}
+
+func (rss rotatedSecretSource) Secrets() []string {
+	return rss
+}
+
+// countingKeyring is a njwt.Keyring that counts the times all the keys are listed.
+type countingKeyring struct {
+	njwt.Keyring
+	listed int
+}
+
+func (ck *countingKeyring) VerificationKeys() ([]*njwt.Key, error) {
+	ck.listed++
+	return ck.Keyring.VerificationKeys()
+}
@@ -14,7 +14,7 @@
  * limitations under the License.
  */
 
-//go:generate mockgen -destination secret_provider_mock_test.go -package=interceptors github.com/napptive/njwt/pkg/interceptors SecretProvider
+//go:generate mockgen -destination secret_provider_mock_test.go -package=interceptors github.com/napptive/njwt/pkg/interceptors SecretProvider,ContextSecretProvider
 //go:generate mockgen -destination secret_client_mock_test.go -package=interceptors github.com/napptive/grpc-jwt-go SecretsClient
 
 package interceptors
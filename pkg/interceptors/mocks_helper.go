@@ -9,7 +9,8 @@ import (
 	"time"
 )
 
-// GetTestJWTConfig returns a JWTConfig to use in the tests
+// GetTestJWTConfig returns a JWTConfig to use in the tests. The tests of other modules can use
+// njwttest.Issuer.Config instead.
 func GetTestJWTConfig() config.JWTConfig {
 	return config.JWTConfig{
 		Secret: "mysecret",
@@ -17,7 +18,8 @@ func GetTestJWTConfig() config.JWTConfig {
 	}
 }
 
-// GetTestAuthxClaim returns a random AuthxClaim to use in the tests
+// GetTestAuthxClaim returns a random AuthxClaim to use in the tests. The tests of other modules can use
+// njwttest.NewAuthxClaim instead.
 func GetTestAuthxClaim() *njwt.AuthxClaim {
 	accounts := make([]njwt.UserAccountClaim, 0)
 	accounts = append(accounts, njwt.UserAccountClaim{
@@ -37,6 +39,8 @@ func GetTestAuthxClaim() *njwt.AuthxClaim {
 		accounts)
 }
 
+// CreateTestIncomingContext returns a context with the token in the incoming metadata. The tests of other
+// modules can use the njwttest.Server harness instead.
 func CreateTestIncomingContext(header string, token string) (context.Context, context.CancelFunc) {
 	md := metadata.New(map[string]string{header: token})
 
@@ -44,6 +48,8 @@ func CreateTestIncomingContext(header string, token string) (context.Context, co
 	return metadata.NewIncomingContext(parentCtx, md), cancel
 }
 
+// CreateTestOutgoingContext returns a context with the token in the outgoing metadata. The tests of other
+// modules can use njwttest.Server.Context instead.
 func CreateTestOutgoingContext(header string, token string) (context.Context, context.CancelFunc) {
 	md := metadata.New(map[string]string{header: token, "Test": "test"})
 
@@ -0,0 +1,248 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"time"
+
+	"github.com/napptive/njwt/pkg/helper"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/zerolog/log"
+)
+
+// Option defines a function that modifies the default behavior of the interceptors.
+type Option func(*options)
+
+// options with the optional settings shared by the JWT interceptors.
+type options struct {
+	// keyring with the keys used to verify the tokens attending to their kid header.
+	keyring njwt.Keyring
+	// secretSource with the secrets used to verify the tokens instead of the secret of the configuration.
+	secretSource njwt.SecretSource
+	// validation with the checks applied to the standard claims of the tokens.
+	validation *njwt.ValidationOptions
+	// revocations with the store of revoked token identifiers.
+	revocations njwt.RevocationStore
+	// sessionEpochs with the store of the session epochs of the users.
+	sessionEpochs njwt.SessionEpochStore
+	// reservedKeys with the metadata keys that clients are not allowed to send.
+	reservedKeys []string
+	// rejectReservedKeys determines if requests containing reserved keys are rejected instead of sanitized.
+	rejectReservedKeys bool
+	// injectMetadata determines if the claim information is injected in the incoming metadata.
+	injectMetadata bool
+	// publicMethods with the full method names that can be called without a token.
+	publicMethods map[string]bool
+	// publicMethodPrefixes with the prefixes of the full method names that can be called without a token.
+	publicMethodPrefixes []string
+	// optionalAuthentication determines if the tokens sent to public methods are verified.
+	optionalAuthentication bool
+	// tokenCookie with the name of the cookie that contains the token in HTTP requests.
+	tokenCookie string
+	// tokenQueryParameter with the name of the query parameter that contains the token in HTTP requests.
+	tokenQueryParameter string
+	// enforceStreamExpiration determines if the streams are cancelled when their token expires.
+	enforceStreamExpiration bool
+	// clock with the current time used to validate the tokens. If nil, the system clock is used.
+	clock njwt.Clock
+	// keyedTokenMgr with the token manager that verifies the tokens with the keyring. It is built once when
+	// the interceptor is created.
+	keyedTokenMgr njwt.KeyedTokenManager
+}
+
+// newOptions creates the interceptor settings applying the given options.
+func newOptions(opts ...Option) *options {
+	result := &options{
+		reservedKeys:   helper.ReservedKeys(),
+		injectMetadata: true,
+		publicMethods:  make(map[string]bool, 0),
+	}
+	for _, opt := range opts {
+		opt(result)
+	}
+	if result.keyring != nil {
+		tokenMgr, err := njwt.NewWithKeyring(result.keyring)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to create the token manager of the keyring")
+		}
+		result.keyedTokenMgr = tokenMgr
+	}
+	return result
+}
+
+// WithKeyring makes the interceptors verify the tokens with the key of the keyring referenced by the
+// kid header of the token. This enables the rotation of the signing keys without invalidating the tokens
+// signed with the previous ones. The JwtInterceptor uses the keyring instead of the secret of the
+// configuration, while the ZoneAwareJWTInterceptor keeps using the zone secret for the tokens without kid.
+func WithKeyring(keyring njwt.Keyring) Option {
+	return func(o *options) {
+		o.keyring = keyring
+	}
+}
+
+// WithSecretSource makes the JwtInterceptor verify the tokens with the secrets of the source instead of the
+// secret of the configuration, so that the secret can be rotated without restarting the service.
+func WithSecretSource(source njwt.SecretSource) Option {
+	return func(o *options) {
+		o.secretSource = source
+	}
+}
+
+// WithValidationOptions sets the checks applied to the standard claims of the tokens, such as the accepted
+// issuers and audiences, or the clock skew tolerated when checking the expiration.
+func WithValidationOptions(validation *njwt.ValidationOptions) Option {
+	return func(o *options) {
+		o.validation = validation
+	}
+}
+
+// WithRevocationStore makes the interceptors reject the tokens whose identifier (jti) has been revoked.
+func WithRevocationStore(store njwt.RevocationStore) Option {
+	return func(o *options) {
+		o.revocations = store
+	}
+}
+
+// WithSessionEpochStore makes the interceptors reject the tokens issued before the session epoch of their
+// user, so that all the sessions of a user can be invalidated at once.
+func WithSessionEpochStore(store njwt.SessionEpochStore) Option {
+	return func(o *options) {
+		o.sessionEpochs = store
+	}
+}
+
+// WithClock sets the clock used to validate the tokens and to schedule the expiration of the streams, so that
+// the tests do not depend on the time of the system. The clock of the validation options takes precedence if
+// both are set.
+func WithClock(clock njwt.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// validationOptions returns the validation options of the tokens, including the revocation store and the clock.
+func (o *options) validationOptions() *njwt.ValidationOptions {
+	if o.revocations == nil && o.clock == nil {
+		return o.validation
+	}
+	validation := njwt.ValidationOptions{}
+	if o.validation != nil {
+		validation = *o.validation
+	}
+	if o.revocations != nil {
+		validation.Revocations = o.revocations
+	}
+	if validation.Clock == nil {
+		validation.Clock = o.clock
+	}
+	return &validation
+}
+
+// currentClock returns the clock used by the interceptors to validate the tokens.
+func (o *options) currentClock() njwt.Clock {
+	if validation := o.validationOptions(); validation != nil && validation.Clock != nil {
+		return validation.Clock
+	}
+	return njwt.SystemClock
+}
+
+// WithReservedKeys sets the metadata keys that clients are not allowed to send, replacing the default ones
+// defined by helper.ReservedKeys. The values sent by the clients with those keys are removed before injecting
+// the claim information, so that handlers only receive the values of the verified claim.
+func WithReservedKeys(keys ...string) Option {
+	return func(o *options) {
+		o.reservedKeys = keys
+	}
+}
+
+// WithRejectReservedKeys makes the interceptors reject the requests that contain any reserved key instead of
+// removing them from the metadata.
+func WithRejectReservedKeys() Option {
+	return func(o *options) {
+		o.rejectReservedKeys = true
+	}
+}
+
+// WithoutMetadataInjection disables the injection of the claim information in the incoming metadata. Handlers
+// must use ClaimFromContext or AuthxClaimFromContext to access the verified claim. By default, the
+// information is injected for backward compatibility with the services that read it from the metadata. The
+// reserved keys sent by the clients are still removed or rejected.
+func WithoutMetadataInjection() Option {
+	return func(o *options) {
+		o.injectMetadata = false
+	}
+}
+
+// WithPublicMethods sets the full method names that can be called without a token, for example,
+// "/grpc.health.v1.Health/Check".
+func WithPublicMethods(fullMethods ...string) Option {
+	return func(o *options) {
+		for _, method := range fullMethods {
+			o.publicMethods[method] = true
+		}
+	}
+}
+
+// WithPublicMethodPrefixes sets the prefixes of the full method names that can be called without a token, for
+// example, "/grpc.reflection.v1alpha.ServerReflection/" to allow all the methods of a service.
+func WithPublicMethodPrefixes(prefixes ...string) Option {
+	return func(o *options) {
+		o.publicMethodPrefixes = append(o.publicMethodPrefixes, prefixes...)
+	}
+}
+
+// WithOptionalAuthentication makes the interceptors verify the token sent to a public method if present. Calls
+// with an invalid token are rejected, while calls without a token reach the handler without claim.
+func WithOptionalAuthentication() Option {
+	return func(o *options) {
+		o.optionalAuthentication = true
+	}
+}
+
+// WithStreamExpiration makes the stream interceptors cancel the context of the stream when its token expires,
+// taking into account the leeway of the validation options. Long-lived streams can be extended by sending a
+// new token to the handler, which calls RefreshStreamToken.
+func WithStreamExpiration() Option {
+	return func(o *options) {
+		o.enforceStreamExpiration = true
+	}
+}
+
+// streamLeeway returns the clock skew tolerated when checking the expiration of the streams.
+func (o *options) streamLeeway() time.Duration {
+	if o.validation == nil {
+		return 0
+	}
+	return o.validation.Leeway
+}
+
+// WithTokenCookie makes the HTTP middlewares read the token from the given cookie if the request does not
+// contain the header of the configuration.
+func WithTokenCookie(name string) Option {
+	return func(o *options) {
+		o.tokenCookie = name
+	}
+}
+
+// WithTokenQueryParameter makes the HTTP middlewares read the token from the given query parameter if the
+// request contains neither the header nor the cookie. Query parameters are usually logged by proxies, so this
+// option should be limited to the cases in which headers cannot be set, such as browser websockets.
+func WithTokenQueryParameter(name string) Option {
+	return func(o *options) {
+		o.tokenQueryParameter = name
+	}
+}
@@ -16,6 +16,12 @@
 
 package interceptors
 
+import (
+	"context"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+)
+
 // SecretProvider defines the methods required for a secret provider. This enables the JWT
 // interceptor to retrieve the secret that corresponds to the signing zone to check the
 // validity of the token.
@@ -24,3 +30,86 @@ type SecretProvider interface {
 	// it can be used in the token validation process.
 	GetZoneSecret(zoneID string) (*string, error)
 }
+
+// ContextSecretProvider defines the methods required for a secret provider that attends to the deadline,
+// cancellation and values of the context of the request that is being verified.
+type ContextSecretProvider interface {
+	// GetZoneSecretWithContext retrieves the signing secret associated with a Zone so that it can be used in the
+	// token validation process.
+	GetZoneSecretWithContext(ctx context.Context, zoneID string) (*string, error)
+}
+
+// NewContextSecretProvider returns a ContextSecretProvider for a SecretProvider. Providers that already
+// implement ContextSecretProvider are returned as they are, while the others are wrapped so that the
+// context is only checked before calling them.
+func NewContextSecretProvider(provider SecretProvider) ContextSecretProvider {
+	if contextProvider, ok := provider.(ContextSecretProvider); ok {
+		return contextProvider
+	}
+	return &contextSecretProviderAdapter{provider: provider}
+}
+
+// contextSecretProviderAdapter adapts a SecretProvider to the ContextSecretProvider interface.
+type contextSecretProviderAdapter struct {
+	provider SecretProvider
+}
+
+// GetZoneSecretWithContext retrieves the signing secret of a zone if the context is still active.
+func (cspa *contextSecretProviderAdapter) GetZoneSecretWithContext(ctx context.Context, zoneID string) (*string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError(err)
+	}
+	return cspa.provider.GetZoneSecret(zoneID)
+}
+
+// NewSecretProvider returns a SecretProvider for a ContextSecretProvider, so that it can be used by the
+// interceptors. The returned provider implements both interfaces, and the interceptors pass the context of
+// the request. Calls without context use a background context limited by ClientTimeout.
+func NewSecretProvider(provider ContextSecretProvider) SecretProvider {
+	if secretProvider, ok := provider.(SecretProvider); ok {
+		return secretProvider
+	}
+	return &secretProviderAdapter{provider}
+}
+
+// secretProviderAdapter adapts a ContextSecretProvider to the SecretProvider interface.
+type secretProviderAdapter struct {
+	ContextSecretProvider
+}
+
+// GetZoneSecret retrieves the signing secret of a zone with a background context.
+func (spa *secretProviderAdapter) GetZoneSecret(zoneID string) (*string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ClientTimeout)
+	defer cancel()
+	return spa.GetZoneSecretWithContext(ctx, zoneID)
+}
+
+// secretRetrievalError transforms the error returned by a secret provider into an extended error, keeping the
+// code of the extended errors, the gRPC status errors and the context errors.
+func secretRetrievalError(err error) *nerrors.ExtendedError {
+	if extended, ok := err.(*nerrors.ExtendedError); ok {
+		return extended
+	}
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return nerrors.FromError(contextError(err))
+	}
+	return nerrors.FromGRPC(err)
+}
+
+// isRetrievalFailure checks if an error retrieving the key of a token is caused by the request or the
+// availability of the key source instead of by the token.
+func isRetrievalFailure(err error) bool {
+	extended, ok := err.(*nerrors.ExtendedError)
+	if !ok {
+		return false
+	}
+	return extended.Code == nerrors.Canceled || extended.Code == nerrors.DeadlineExceeded || extended.Code == nerrors.Unavailable
+}
+
+// contextError transforms the error of a context into the corresponding extended error.
+func contextError(err error) error {
+	if err == context.DeadlineExceeded {
+		return nerrors.NewDeadlineExceededErrorFrom(err, "deadline exceeded retrieving zone secret")
+	}
+	return nerrors.NewCanceledErrorFrom(err, "request cancelled retrieving zone secret")
+}
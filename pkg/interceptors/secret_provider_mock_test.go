@@ -1,10 +1,11 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/napptive/njwt/pkg/interceptors (interfaces: SecretProvider)
+// Source: github.com/napptive/njwt/pkg/interceptors (interfaces: SecretProvider,ContextSecretProvider)
 
 // Package interceptors is a generated GoMock package.
 package interceptors
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -47,3 +48,41 @@ func (mr *MockSecretProviderMockRecorder) GetZoneSecret(arg0 interface{}) *gomoc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetZoneSecret", reflect.TypeOf((*MockSecretProvider)(nil).GetZoneSecret), arg0)
 }
+
+// MockContextSecretProvider is a mock of ContextSecretProvider interface.
+type MockContextSecretProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockContextSecretProviderMockRecorder
+}
+
+// MockContextSecretProviderMockRecorder is the mock recorder for MockContextSecretProvider.
+type MockContextSecretProviderMockRecorder struct {
+	mock *MockContextSecretProvider
+}
+
+// NewMockContextSecretProvider creates a new mock instance.
+func NewMockContextSecretProvider(ctrl *gomock.Controller) *MockContextSecretProvider {
+	mock := &MockContextSecretProvider{ctrl: ctrl}
+	mock.recorder = &MockContextSecretProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockContextSecretProvider) EXPECT() *MockContextSecretProviderMockRecorder {
+	return m.recorder
+}
+
+// GetZoneSecretWithContext mocks base method.
+func (m *MockContextSecretProvider) GetZoneSecretWithContext(arg0 context.Context, arg1 string) (*string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetZoneSecretWithContext", arg0, arg1)
+	ret0, _ := ret[0].(*string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetZoneSecretWithContext indicates an expected call of GetZoneSecretWithContext.
+func (mr *MockContextSecretProviderMockRecorder) GetZoneSecretWithContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetZoneSecretWithContext", reflect.TypeOf((*MockContextSecretProvider)(nil).GetZoneSecretWithContext), arg0, arg1)
+}
@@ -0,0 +1,249 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultZoneName is the name used by the file and environment providers for the secret of the tokens
+// without zone.
+const DefaultZoneName = "default"
+
+// DefaultSecretEnvPrefix is the default prefix of the environment variables read by the EnvSecretProvider.
+const DefaultSecretEnvPrefix = "NJWT_ZONE_SECRET_"
+
+// StaticSecretProvider is a SecretProvider backed by an in-memory map of zone identifiers to secrets.
+type StaticSecretProvider struct {
+	secrets map[string]string
+}
+
+// NewStaticSecretProvider creates a provider with a copy of the given secrets. The secret of the tokens without
+// zone is stored with the empty identifier.
+func NewStaticSecretProvider(secrets map[string]string) *StaticSecretProvider {
+	copied := make(map[string]string, len(secrets))
+	for zoneID, secret := range secrets {
+		copied[zoneID] = secret
+	}
+	return &StaticSecretProvider{secrets: copied}
+}
+
+// GetZoneSecret retrieves the signing secret associated with a zone.
+func (ssp *StaticSecretProvider) GetZoneSecret(zoneID string) (*string, error) {
+	secret, exists := ssp.secrets[zoneID]
+	if !exists {
+		return nil, nerrors.NewNotFoundError("secret of zone [%s] not found", zoneID)
+	}
+	return &secret, nil
+}
+
+// FileSecretProvider is a SecretProvider that reads the secret of each zone from a file named after the zone
+// identifier in a directory, such as the one in which a Kubernetes secret is mounted. The files are read on
+// each call, so it is usually combined with NewCachingSecretProvider.
+type FileSecretProvider struct {
+	directory string
+}
+
+// NewFileSecretProvider creates a provider that reads the secrets from the given directory. The secret of the
+// tokens without zone is read from the file named DefaultZoneName.
+func NewFileSecretProvider(directory string) *FileSecretProvider {
+	return &FileSecretProvider{directory: directory}
+}
+
+// GetZoneSecret retrieves the signing secret associated with a zone.
+func (fsp *FileSecretProvider) GetZoneSecret(zoneID string) (*string, error) {
+	name, err := zoneName(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(fsp.directory, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nerrors.NewNotFoundError("secret of zone [%s] not found", zoneID)
+		}
+		return nil, nerrors.NewInternalErrorFrom(err, "unable to read secret of zone [%s]", zoneID)
+	}
+	secret := strings.TrimSpace(string(content))
+	if secret == "" {
+		return nil, nerrors.NewNotFoundError("secret of zone [%s] is empty", zoneID)
+	}
+	return &secret, nil
+}
+
+// EnvSecretProvider is a SecretProvider that reads the secret of each zone from an environment variable
+// named after the prefix and the zone identifier in uppercase, replacing the characters that are not letters
+// or digits with underscores. For example, the secret of zone "eu-1" is read from NJWT_ZONE_SECRET_EU_1.
+type EnvSecretProvider struct {
+	prefix string
+}
+
+// NewEnvSecretProvider creates a provider that reads the secrets from the environment variables with the
+// given prefix, or DefaultSecretEnvPrefix if empty. The secret of the tokens without zone is read from the
+// variable named after DefaultZoneName.
+func NewEnvSecretProvider(prefix string) *EnvSecretProvider {
+	if prefix == "" {
+		prefix = DefaultSecretEnvPrefix
+	}
+	return &EnvSecretProvider{prefix: prefix}
+}
+
+// GetZoneSecret retrieves the signing secret associated with a zone.
+func (esp *EnvSecretProvider) GetZoneSecret(zoneID string) (*string, error) {
+	name, err := zoneName(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	variable := esp.prefix + strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+	secret, exists := os.LookupEnv(variable)
+	if !exists || secret == "" {
+		return nil, nerrors.NewNotFoundError("secret of zone [%s] not found", zoneID)
+	}
+	return &secret, nil
+}
+
+// zoneName returns the name used to store the secret of a zone. As the zone identifier is taken from the
+// token before verifying it, identifiers that could reference other files are rejected.
+func zoneName(zoneID string) (string, error) {
+	if zoneID == "" {
+		return DefaultZoneName, nil
+	}
+	if zoneID == "." || zoneID == ".." || strings.ContainsAny(zoneID, `/\`) || strings.ContainsRune(zoneID, 0) {
+		return "", nerrors.NewInvalidArgumentError("invalid zone identifier")
+	}
+	return zoneID, nil
+}
+
+// ChainSecretProvider is a SecretProvider that tries a list of providers in order, returning the first
+// secret found.
+type ChainSecretProvider struct {
+	providers []ContextSecretProvider
+}
+
+// NewChainSecretProvider creates a provider that tries the given providers in order.
+// Example:
+//
+//	provider := interceptors.NewChainSecretProvider(
+//		interceptors.NewEnvSecretProvider(""),
+//		interceptors.NewCachingSecretProvider(interceptors.NewFileSecretProvider("/etc/njwt/zones"), time.Minute))
+func NewChainSecretProvider(providers ...SecretProvider) *ChainSecretProvider {
+	contextProviders := make([]ContextSecretProvider, 0, len(providers))
+	for _, provider := range providers {
+		contextProviders = append(contextProviders, NewContextSecretProvider(provider))
+	}
+	return &ChainSecretProvider{providers: contextProviders}
+}
+
+// GetZoneSecret retrieves the signing secret associated with a zone.
+func (csp *ChainSecretProvider) GetZoneSecret(zoneID string) (*string, error) {
+	return csp.GetZoneSecretWithContext(context.Background(), zoneID)
+}
+
+// GetZoneSecretWithContext retrieves the signing secret associated with a zone from the first provider that
+// contains it. If none of them returns the secret, the first error that is not a NotFound one is returned.
+func (csp *ChainSecretProvider) GetZoneSecretWithContext(ctx context.Context, zoneID string) (*string, error) {
+	var firstErr error
+	for _, provider := range csp.providers {
+		secret, err := provider.GetZoneSecretWithContext(ctx, zoneID)
+		if err == nil {
+			return secret, nil
+		}
+		if nerrors.FromError(err).Code != nerrors.NotFound {
+			log.Warn().Err(err).Str("zone_id", zoneID).Msg("unable to retrieve zone secret, trying the next provider")
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, nerrors.NewNotFoundError("secret of zone [%s] not found", zoneID)
+}
+
+// CachingSecretProvider is a SecretProvider that caches the secrets returned by another provider for a period
+// of time. Errors are not cached.
+type CachingSecretProvider struct {
+	sync.RWMutex
+	provider ContextSecretProvider
+	ttl      time.Duration
+	cache    map[string]*CachedSecret
+	// clock with the current time used to compute the age of the entries.
+	clock njwt.Clock
+}
+
+// NewCachingSecretProvider creates a provider that caches the secrets of the given one. Expired entries are
+// replaced on the next request of the zone, so no background goroutine is required.
+func NewCachingSecretProvider(provider SecretProvider, ttl time.Duration) *CachingSecretProvider {
+	return NewCachingSecretProviderWithClock(provider, ttl, njwt.SystemClock)
+}
+
+// NewCachingSecretProviderWithClock creates a provider like NewCachingSecretProvider that computes the age of
+// the entries with the given clock, so that the tests do not have to wait for the entries to expire.
+func NewCachingSecretProviderWithClock(provider SecretProvider, ttl time.Duration, clock njwt.Clock) *CachingSecretProvider {
+	if clock == nil {
+		clock = njwt.SystemClock
+	}
+	return &CachingSecretProvider{
+		provider: NewContextSecretProvider(provider),
+		ttl:      ttl,
+		cache:    make(map[string]*CachedSecret),
+		clock:    clock,
+	}
+}
+
+// GetZoneSecret retrieves the signing secret associated with a zone.
+func (csp *CachingSecretProvider) GetZoneSecret(zoneID string) (*string, error) {
+	return csp.GetZoneSecretWithContext(context.Background(), zoneID)
+}
+
+// GetZoneSecretWithContext retrieves the signing secret associated with a zone, using the cached one if it has
+// not expired.
+func (csp *CachingSecretProvider) GetZoneSecretWithContext(ctx context.Context, zoneID string) (*string, error) {
+	csp.RLock()
+	cached, exists := csp.cache[zoneID]
+	csp.RUnlock()
+	if exists && csp.clock.Now().Sub(cached.timestamp) < csp.ttl {
+		secret := cached.secret
+		return &secret, nil
+	}
+	secret, err := csp.provider.GetZoneSecretWithContext(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	csp.Lock()
+	csp.cache[zoneID] = &CachedSecret{
+		timestamp: csp.clock.Now(),
+		secret:    *secret,
+	}
+	csp.Unlock()
+	return secret, nil
+}
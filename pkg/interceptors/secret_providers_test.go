@@ -0,0 +1,104 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// expectZoneSecret checks that a provider returns the expected secret of a zone.
+func expectZoneSecret(provider SecretProvider, zoneID string, expected string) {
+	secret, err := provider.GetZoneSecret(zoneID)
+	gomega.Expect(err).To(gomega.Succeed())
+	gomega.Expect(*secret).Should(gomega.Equal(expected))
+}
+
+// expectZoneSecretError checks that a provider fails to return the secret of a zone with the given code.
+func expectZoneSecretError(provider SecretProvider, zoneID string, code nerrors.ErrorCode) {
+	_, err := provider.GetZoneSecret(zoneID)
+	gomega.Expect(err).NotTo(gomega.Succeed())
+	gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(code))
+}
+
+var _ = ginkgo.Describe("Secret providers", func() {
+
+	ginkgo.It("should return the secrets of a static map", func() {
+		provider := NewStaticSecretProvider(map[string]string{"": "default", "zone": "zoneSecret"})
+		expectZoneSecret(provider, "", "default")
+		expectZoneSecret(provider, "zone", "zoneSecret")
+		expectZoneSecretError(provider, "other", nerrors.NotFound)
+	})
+
+	ginkgo.It("should read the secrets of a directory", func() {
+		dir, err := os.MkdirTemp("", "zones")
+		gomega.Expect(err).To(gomega.Succeed())
+		defer os.RemoveAll(dir)
+		gomega.Expect(os.WriteFile(filepath.Join(dir, "zone"), []byte("zoneSecret\n"), 0600)).To(gomega.Succeed())
+		gomega.Expect(os.WriteFile(filepath.Join(dir, DefaultZoneName), []byte("default"), 0600)).To(gomega.Succeed())
+
+		provider := NewFileSecretProvider(dir)
+		expectZoneSecret(provider, "zone", "zoneSecret")
+		expectZoneSecret(provider, "", "default")
+		expectZoneSecretError(provider, "other", nerrors.NotFound)
+		expectZoneSecretError(provider, "../zone", nerrors.InvalidArgument)
+		expectZoneSecretError(provider, "..", nerrors.InvalidArgument)
+	})
+
+	ginkgo.It("should read the secrets of the environment", func() {
+		os.Setenv("NJWT_TEST_ZONE_EU_1", "zoneSecret")
+		defer os.Unsetenv("NJWT_TEST_ZONE_EU_1")
+		provider := NewEnvSecretProvider("NJWT_TEST_ZONE_")
+		expectZoneSecret(provider, "eu-1", "zoneSecret")
+		expectZoneSecretError(provider, "other", nerrors.NotFound)
+	})
+
+	ginkgo.It("should try the providers of a chain in order", func() {
+		ctrl := gomock.NewController(ginkgo.GinkgoT())
+		failing := NewMockSecretProvider(ctrl)
+		failing.EXPECT().GetZoneSecret(gomock.Any()).Return(nil, nerrors.NewInternalError("unavailable")).AnyTimes()
+		first := NewStaticSecretProvider(map[string]string{"zone": "first"})
+		second := NewStaticSecretProvider(map[string]string{"zone": "second", "other": "other"})
+
+		provider := NewChainSecretProvider(failing, first, second)
+		expectZoneSecret(provider, "zone", "first")
+		expectZoneSecret(provider, "other", "other")
+		expectZoneSecretError(provider, "unknown", nerrors.Internal)
+		expectZoneSecretError(NewChainSecretProvider(first, second), "unknown", nerrors.NotFound)
+	})
+
+	ginkgo.It("should cache the secrets of a provider", func() {
+		ctrl := gomock.NewController(ginkgo.GinkgoT())
+		secret := "zoneSecret"
+		mock := NewMockSecretProvider(ctrl)
+		mock.EXPECT().GetZoneSecret("zone").Return(&secret, nil).Times(2)
+
+		clock := njwt.NewFakeClock(time.Now())
+		provider := NewCachingSecretProviderWithClock(mock, time.Minute, clock)
+		expectZoneSecret(provider, "zone", secret)
+		expectZoneSecret(provider, "zone", secret)
+		clock.Advance(2 * time.Minute)
+		expectZoneSecret(provider, "zone", secret)
+	})
+})
@@ -24,11 +24,16 @@ import (
 	"github.com/napptive/grpc-jwt-go"
 	"github.com/napptive/nerrors/pkg/nerrors"
 	"github.com/napptive/njwt/pkg/config"
+	"github.com/napptive/njwt/pkg/njwt"
 	"github.com/rs/zerolog/log"
 )
 
 const ClientTimeout = 30 * time.Second
 
+// DefaultRefreshBackoff is the default time during which a zone secret is not renewed again in background after
+// a failed renewal.
+const DefaultRefreshBackoff = 5 * time.Second
+
 // CachedSecret stores a JWT secret with the timestamp in which it has been retrieved.
 type CachedSecret struct {
 	timestamp time.Time
@@ -43,6 +48,54 @@ func (cs *CachedSecret) Clone() *CachedSecret {
 	}
 }
 
+// secretFetch is an in-flight retrieval of a zone secret whose result is shared by the concurrent requests
+// of the same zone.
+type secretFetch struct {
+	// done is closed once the retrieval finishes.
+	done   chan struct{}
+	secret string
+	err    error
+}
+
+// SecretManagerOption defines a function that modifies the default behavior of the InterceptorZoneSecretManager.
+type SecretManagerOption func(*InterceptorZoneSecretManager)
+
+// WithRefreshAhead makes the manager renew in background the secrets requested during the last window of their
+// time to live, so that frequently used zones never block on the secrets service.
+func WithRefreshAhead(window time.Duration) SecretManagerOption {
+	return func(izsm *InterceptorZoneSecretManager) {
+		izsm.refreshAhead = window
+	}
+}
+
+// WithStaleGracePeriod makes the manager keep serving the last known secret of a zone for the given period
+// after its expiration while it is renewed in background. If the secrets service rejects the renewal, for
+// example, because the zone is unknown, the secret is dropped so that the following requests get the error.
+func WithStaleGracePeriod(grace time.Duration) SecretManagerOption {
+	return func(izsm *InterceptorZoneSecretManager) {
+		izsm.staleGracePeriod = grace
+	}
+}
+
+// WithRefreshBackoff sets the time during which a zone secret is not renewed again in background after a failed
+// renewal, so that an unavailable secrets service is not called on every request.
+func WithRefreshBackoff(backoff time.Duration) SecretManagerOption {
+	return func(izsm *InterceptorZoneSecretManager) {
+		izsm.refreshBackoff = backoff
+	}
+}
+
+// WithCacheClock sets the clock used to compute the age of the cached secrets, so that the tests do not have to
+// wait for the entries to expire.
+func WithCacheClock(clock njwt.Clock) SecretManagerOption {
+	return func(izsm *InterceptorZoneSecretManager) {
+		if clock == nil {
+			clock = njwt.SystemClock
+		}
+		izsm.clock = clock
+	}
+}
+
 // InterceptorZoneSecretManager offers a cached zone JWT signing secret retrieval interface. Elements
 // retrieved from the SecretsClient are stored in an internal cache for a period of time before being evicted.
 type InterceptorZoneSecretManager struct {
@@ -53,76 +106,329 @@ type InterceptorZoneSecretManager struct {
 	secretsClient grpc_jwt_go.SecretsClient
 	zoneCacheTTL  time.Duration
 	SecretCache   map[string]*CachedSecret
+	// inflight with the retrievals in progress, indexed by zone identifier.
+	inflight map[string]*secretFetch
+	// refreshAhead with the window before the expiration of an entry in which it is renewed in background.
+	refreshAhead time.Duration
+	// staleGracePeriod with the time after the expiration of an entry in which it is served while it is renewed
+	// in background.
+	staleGracePeriod time.Duration
+	// refreshBackoff with the time after a failed renewal in which the entry is not renewed again in background.
+	refreshBackoff time.Duration
+	// refreshFailures with the time of the last failed renewal of each zone.
+	refreshFailures map[string]time.Time
+	// clock with the current time used to compute the age of the entries.
+	clock njwt.Clock
+	// stop is closed to stop the eviction loop.
+	stop     chan struct{}
+	stopOnce sync.Once
+	// done is closed once the eviction loop finishes.
+	done chan struct{}
+	// closing is cancelled when the manager is closed to abort the retrievals in progress.
+	closing      context.Context
+	cancelClosed context.CancelFunc
+	// closed is set once the manager is closed, so that no new background tasks are launched.
+	closed bool
+	// tasks with the background refreshes and retrievals in progress.
+	tasks sync.WaitGroup
 }
 
 // NewInterceptorZoneSecretManager creates a zone manager that communicates with the secrets service
 // to retrieve zone signing secrets.
-func NewInterceptorZoneSecretManager(config config.JWTConfig, secretsClient grpc_jwt_go.SecretsClient, zoneCacheTTL time.Duration) SecretProvider {
+//
+// Deprecated: The eviction loop of the manager is never stopped. Use NewZoneSecretManager instead.
+func NewInterceptorZoneSecretManager(config config.JWTConfig, secretsClient grpc_jwt_go.SecretsClient, zoneCacheTTL time.Duration, opts ...SecretManagerOption) SecretProvider {
+	manager := newInterceptorZoneSecretManager(config, secretsClient, zoneCacheTTL, opts...)
+	if zoneCacheTTL <= 0 {
+		log.Warn().Dur("zone_cache_ttl", zoneCacheTTL).Msg("invalid zone cache TTL, entries will not be evicted")
+		return manager
+	}
+	manager.startEvictLoop(context.Background())
+	return manager
+}
+
+// NewZoneSecretManager creates a zone manager that communicates with the secrets service to retrieve zone
+// signing secrets. The entries of the cache are evicted in background until the context is cancelled or the
+// manager is closed.
+// Example:
+//
+//	manager, err := interceptors.NewZoneSecretManager(ctx, cfg, secretsClient, time.Hour, interceptors.WithRefreshAhead(time.Minute))
+//	if err != nil {
+//		return err
+//	}
+//	defer manager.Close()
+func NewZoneSecretManager(ctx context.Context, config config.JWTConfig, secretsClient grpc_jwt_go.SecretsClient, zoneCacheTTL time.Duration, opts ...SecretManagerOption) (*InterceptorZoneSecretManager, error) {
+	if secretsClient == nil {
+		return nil, nerrors.NewInvalidArgumentError("secrets client must be provided")
+	}
+	manager := newInterceptorZoneSecretManager(config, secretsClient, zoneCacheTTL, opts...)
+	if err := manager.IsValid(); err != nil {
+		return nil, err
+	}
+	manager.startEvictLoop(ctx)
+	return manager, nil
+}
+
+// newInterceptorZoneSecretManager creates a zone manager applying the given options, without starting the
+// eviction loop.
+func newInterceptorZoneSecretManager(config config.JWTConfig, secretsClient grpc_jwt_go.SecretsClient, zoneCacheTTL time.Duration, opts ...SecretManagerOption) *InterceptorZoneSecretManager {
+	closing, cancelClosed := context.WithCancel(context.Background())
 	manager := &InterceptorZoneSecretManager{
-		config:        config,
-		secretsClient: secretsClient,
-		zoneCacheTTL:  zoneCacheTTL,
-		SecretCache:   make(map[string]*CachedSecret),
+		config:          config,
+		secretsClient:   secretsClient,
+		zoneCacheTTL:    zoneCacheTTL,
+		SecretCache:     make(map[string]*CachedSecret),
+		inflight:        make(map[string]*secretFetch),
+		refreshBackoff:  DefaultRefreshBackoff,
+		refreshFailures: make(map[string]time.Time),
+		stop:            make(chan struct{}),
+		clock:           njwt.SystemClock,
+		closing:         closing,
+		cancelClosed:    cancelClosed,
+	}
+	for _, opt := range opts {
+		opt(manager)
 	}
-	go manager.evictLoop()
 	return manager
 }
 
-// evictLoop cleans the cache triggering the eviction method.
-func (izsm *InterceptorZoneSecretManager) evictLoop() {
-	ticker := time.NewTicker(izsm.zoneCacheTTL / 2)
-	for range ticker.C {
-		izsm.Evict()
+// IsValid checks that the settings of the manager are valid.
+func (izsm *InterceptorZoneSecretManager) IsValid() error {
+	if izsm.zoneCacheTTL <= 0 {
+		return nerrors.NewInvalidArgumentError("zone cache TTL must be positive")
+	}
+	if izsm.refreshAhead < 0 || izsm.refreshAhead >= izsm.zoneCacheTTL {
+		return nerrors.NewInvalidArgumentError("refresh ahead window must be shorter than the zone cache TTL")
+	}
+	if izsm.staleGracePeriod < 0 {
+		return nerrors.NewInvalidArgumentError("stale grace period cannot be negative")
+	}
+	if izsm.refreshBackoff < 0 {
+		return nerrors.NewInvalidArgumentError("refresh backoff cannot be negative")
+	}
+	return nil
+}
+
+// startEvictLoop launches the eviction loop in background.
+func (izsm *InterceptorZoneSecretManager) startEvictLoop(ctx context.Context) {
+	izsm.done = make(chan struct{})
+	go izsm.evictLoop(ctx)
+}
+
+// evictLoop cleans the cache triggering the eviction method until the context is cancelled or the manager
+// is closed.
+func (izsm *InterceptorZoneSecretManager) evictLoop(ctx context.Context) {
+	defer close(izsm.done)
+	interval := izsm.zoneCacheTTL / 2
+	if interval <= 0 {
+		interval = izsm.zoneCacheTTL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			izsm.Evict()
+		case <-ctx.Done():
+			return
+		case <-izsm.stop:
+			return
+		}
+	}
+}
+
+// Close stops the eviction loop and aborts the retrievals in progress, waiting for all the background tasks
+// to finish. It is safe to call it more than once.
+func (izsm *InterceptorZoneSecretManager) Close() error {
+	izsm.stopOnce.Do(func() {
+		izsm.Lock()
+		izsm.closed = true
+		izsm.Unlock()
+		close(izsm.stop)
+		izsm.cancelClosed()
+	})
+	if izsm.done != nil {
+		<-izsm.done
+	}
+	izsm.tasks.Wait()
+	return nil
+}
+
+// launch runs a task in background unless the manager is closed, so that Close waits for it. The caller must
+// hold the lock.
+func (izsm *InterceptorZoneSecretManager) launch(task func()) bool {
+	if izsm.closed {
+		return false
 	}
+	izsm.tasks.Add(1)
+	go func() {
+		defer izsm.tasks.Done()
+		task()
+	}()
+	return true
 }
 
-// Evict old entries of the cache attending to the creation timestamp.
+// Evict old entries of the cache attending to the creation timestamp. Expired entries are kept during the
+// stale grace period.
 func (izsm *InterceptorZoneSecretManager) Evict() {
-	timeLimit := time.Now().Add(-1 * izsm.zoneCacheTTL)
+	timeLimit := izsm.clock.Now().Add(-1 * (izsm.zoneCacheTTL + izsm.staleGracePeriod))
 	izsm.Lock()
 	defer izsm.Unlock()
 	for zoneID, secret := range izsm.SecretCache {
 		if secret.timestamp.Before(timeLimit) {
 			delete(izsm.SecretCache, zoneID)
+			delete(izsm.refreshFailures, zoneID)
 		}
 	}
 }
 
 // GetZoneSecret retrieves JWT signing secret associated with a given zone identifier.
 func (izsm *InterceptorZoneSecretManager) GetZoneSecret(zoneID string) (*string, error) {
+	return izsm.GetZoneSecretWithContext(context.Background(), zoneID)
+}
+
+// GetZoneSecretWithContext retrieves JWT signing secret associated with a given zone identifier. The cached
+// secrets that are about to expire, or that are in the stale grace period, are returned while they are renewed
+// in background. If the secret is not cached, the call returns when the context is done even if the retrieval
+// has not finished. As the retrieval is shared with other requests, it is not cancelled with the context, but it
+// keeps its values so that the call to the secrets service carries the tracing information of the first request.
+func (izsm *InterceptorZoneSecretManager) GetZoneSecretWithContext(ctx context.Context, zoneID string) (*string, error) {
+	if zoneID == "" && izsm.config.Secret != "" {
+		// The default secret is never cached, as it does not change.
+		return &izsm.config.Secret, nil
+	}
+
 	izsm.RLock()
-	secret, exists := izsm.SecretCache[zoneID]
+	cached, exists := izsm.SecretCache[zoneID]
+	var cachedSecret CachedSecret
+	if exists {
+		cachedSecret = *cached
+	}
 	izsm.RUnlock()
 
 	if exists {
-		return &secret.secret, nil
+		age := izsm.age(&cachedSecret)
+		if izsm.isFresh(age) {
+			return &cachedSecret.secret, nil
+		}
+		if age < izsm.zoneCacheTTL+izsm.staleGracePeriod {
+			// The entry is about to expire or in the grace period, renew it in background.
+			izsm.startRefresh(ctx, zoneID)
+			return &cachedSecret.secret, nil
+		}
 	}
 
-	if zoneID == "" && izsm.config.Secret != "" {
-		// Preload the cache with the default secret
-		izsm.Lock()
-		izsm.SecretCache[""] = &CachedSecret{
-			timestamp: time.Now(),
-			secret:    izsm.config.Secret,
+	zoneSecret, err := izsm.fetch(ctx, zoneID)
+	if err != nil {
+		return nil, err
+	}
+	return &zoneSecret, nil
+}
+
+// startRefresh launches the renewal of the secret of a zone in background, unless it is already being
+// retrieved or the last renewal failed during the refresh backoff.
+func (izsm *InterceptorZoneSecretManager) startRefresh(ctx context.Context, zoneID string) {
+	refreshCtx := context.WithoutCancel(ctx)
+	izsm.Lock()
+	defer izsm.Unlock()
+	if _, refreshing := izsm.inflight[zoneID]; refreshing {
+		return
+	}
+	if failedAt, failed := izsm.refreshFailures[zoneID]; failed && izsm.clock.Now().Sub(failedAt) < izsm.refreshBackoff {
+		return
+	}
+	izsm.launch(func() { izsm.refresh(refreshCtx, zoneID) })
+}
+
+// isUnavailable checks if an error retrieving a secret is caused by the unavailability of the secrets
+// service, in which case the last known secret can still be served.
+func isUnavailable(err error) bool {
+	code := nerrors.FromError(err).Code
+	return code == nerrors.Unavailable || code == nerrors.DeadlineExceeded
+}
+
+// age returns the time elapsed since a secret was retrieved.
+func (izsm *InterceptorZoneSecretManager) age(cachedSecret *CachedSecret) time.Duration {
+	return izsm.clock.Now().Sub(cachedSecret.timestamp)
+}
+
+// isFresh checks if an entry of a given age can be served without renewing it.
+func (izsm *InterceptorZoneSecretManager) isFresh(age time.Duration) bool {
+	return age < izsm.zoneCacheTTL-izsm.refreshAhead
+}
+
+// refresh renews the secret of a zone in background.
+func (izsm *InterceptorZoneSecretManager) refresh(ctx context.Context, zoneID string) {
+	if _, err := izsm.fetch(ctx, zoneID); err != nil {
+		log.Warn().Err(err).Str("zone_id", zoneID).Msg("unable to refresh zone signing secret")
+	}
+}
+
+// fetch retrieves a zone secret from the secrets service and stores it in the cache. Concurrent requests for
+// the same zone wait for the retrieval in progress instead of calling the service again.
+func (izsm *InterceptorZoneSecretManager) fetch(ctx context.Context, zoneID string) (string, error) {
+	izsm.Lock()
+	call, exists := izsm.inflight[zoneID]
+	if !exists {
+		// The secret may have been stored by a retrieval that finished after checking the cache.
+		if cached, exists := izsm.SecretCache[zoneID]; exists && izsm.isFresh(izsm.age(cached)) {
+			izsm.Unlock()
+			return cached.secret, nil
 		}
-		izsm.Unlock()
-		return &izsm.config.Secret, nil
+		call = &secretFetch{done: make(chan struct{})}
+		retrieveCtx := context.WithoutCancel(ctx)
+		if !izsm.launch(func() { izsm.retrieve(retrieveCtx, zoneID, call) }) {
+			izsm.Unlock()
+			return "", nerrors.NewCanceledError("zone secret manager is closed")
+		}
+		izsm.inflight[zoneID] = call
+	}
+	izsm.Unlock()
+
+	select {
+	case <-call.done:
+		return call.secret, call.err
+	case <-ctx.Done():
+		return "", contextError(ctx.Err())
 	}
-	// Retrieve it from the secret provider
-	ctx, cancel := context.WithTimeout(context.Background(), ClientTimeout)
+}
+
+// retrieve a zone secret from the secrets service, storing it in the cache and sharing the result with
+// the requests waiting for the retrieval. If the secrets service is unavailable, the secret is not renewed again
+// in background during the refresh backoff. If it rejects the request, the cached secret is dropped, as the zone
+// can no longer be trusted.
+func (izsm *InterceptorZoneSecretManager) retrieve(ctx context.Context, zoneID string, call *secretFetch) {
+	ctx, cancel := context.WithTimeout(ctx, ClientTimeout)
 	defer cancel()
+	// The retrieval is aborted if the manager is closed.
+	stop := context.AfterFunc(izsm.closing, cancel)
+	defer stop()
 
 	log.Debug().Str("zone_id", zoneID).Msg("loading zone signing secret from provider")
 	zoneSigningSecret, err := izsm.secretsClient.Get(ctx, &grpc_jwt_go.GetSecretRequest{SecretId: zoneID})
 	if err != nil {
 		log.Error().Err(err).Str("zone_id", zoneID).Msg("unable to retrieve zone signing secret")
-		return nil, nerrors.NewInternalError("cannot verify token")
+		// The code returned by the secrets service is kept to tell apart the unavailability of the service.
+		call.err = nerrors.NewExtendedErrorFrom(nerrors.FromGRPC(err).Code, err, "cannot retrieve zone signing secret")
+	} else {
+		call.secret = zoneSigningSecret.JwtSecret
 	}
+
 	izsm.Lock()
-	izsm.SecretCache[zoneID] = &CachedSecret{
-		timestamp: time.Now(),
-		secret:    zoneSigningSecret.JwtSecret,
+	switch {
+	case call.err == nil:
+		izsm.SecretCache[zoneID] = &CachedSecret{
+			timestamp: izsm.clock.Now(),
+			secret:    call.secret,
+		}
+		delete(izsm.refreshFailures, zoneID)
+	case isUnavailable(call.err):
+		izsm.refreshFailures[zoneID] = izsm.clock.Now()
+	case nerrors.FromError(call.err).Code != nerrors.Canceled:
+		delete(izsm.SecretCache, zoneID)
+		delete(izsm.refreshFailures, zoneID)
 	}
+	delete(izsm.inflight, zoneID)
 	izsm.Unlock()
-	return &zoneSigningSecret.JwtSecret, nil
+	close(call.done)
 }
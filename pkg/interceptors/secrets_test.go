@@ -17,10 +17,17 @@
 package interceptors
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
+
 	"github.com/golang/mock/gomock"
 	grpc_jwt_go "github.com/napptive/grpc-jwt-go"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
 	"github.com/onsi/ginkgo"
 	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
 	"time"
 )
 
@@ -30,18 +37,32 @@ var _ = ginkgo.Describe("Zone aware secrets manager", func() {
 	jwtConfig := GetTestJWTConfig()
 	var ctrl *gomock.Controller
 	var secretsClientMock *MockSecretsClient
-	var secretsManager SecretProvider
+	var secretsManager *InterceptorZoneSecretManager
+	var clock *njwt.FakeClock
+
+	newManager := func(ttl time.Duration, opts ...SecretManagerOption) *InterceptorZoneSecretManager {
+		opts = append([]SecretManagerOption{WithCacheClock(clock)}, opts...)
+		manager, err := NewZoneSecretManager(context.Background(), jwtConfig, secretsClientMock, ttl, opts...)
+		gomega.Expect(err).To(gomega.Succeed())
+		return manager
+	}
 
 	ginkgo.BeforeEach(func() {
 		ctrl = gomock.NewController(ginkgo.GinkgoT())
 		secretsClientMock = NewMockSecretsClient(ctrl)
-		secretsManager = NewInterceptorZoneSecretManager(jwtConfig, secretsClientMock, testCacheTTL)
+		clock = njwt.NewFakeClock(time.Now())
+		secretsManager = newManager(testCacheTTL)
+	})
+
+	ginkgo.AfterEach(func() {
+		gomega.Expect(secretsManager.Close()).To(gomega.Succeed())
 	})
 
 	ginkgo.It("should be able to return the default JWT secret for backward compatibility", func() {
 		secret, err := secretsManager.GetZoneSecret("")
 		gomega.Expect(err).To(gomega.Succeed())
 		gomega.Expect(*secret).Should(gomega.Equal(jwtConfig.Secret))
+		gomega.Expect(secretsManager.SecretCache).ShouldNot(gomega.HaveKey(""))
 	})
 
 	ginkgo.It("should be able to retrieve a zone secret", func() {
@@ -65,10 +86,203 @@ var _ = ginkgo.Describe("Zone aware secrets manager", func() {
 		secret, err := secretsManager.GetZoneSecret("uncached")
 		gomega.Expect(err).To(gomega.Succeed())
 		gomega.Expect(*secret).Should(gomega.Equal(response.JwtSecret))
-		time.Sleep(2 * testCacheTTL)
+		clock.Advance(2 * testCacheTTL)
+		secretsManager.Evict()
+		gomega.Expect(secretsManager.SecretCache).ShouldNot(gomega.HaveKey("uncached"))
 		newSecret, err := secretsManager.GetZoneSecret("uncached")
 		gomega.Expect(err).To(gomega.Succeed())
 		gomega.Expect(*newSecret).Should(gomega.Equal(response.JwtSecret))
 	})
 
+	ginkgo.It("should share the retrieval of a zone secret between concurrent requests", func() {
+		response := &grpc_jwt_go.SecretResponse{
+			JwtSecret: "zoneSecret",
+		}
+		secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, request *grpc_jwt_go.GetSecretRequest, opts ...grpc.CallOption) (*grpc_jwt_go.SecretResponse, error) {
+				time.Sleep(200 * time.Millisecond)
+				return response, nil
+			}).Times(1)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer ginkgo.GinkgoRecover()
+				defer wg.Done()
+				secret, err := secretsManager.GetZoneSecret("uncached")
+				gomega.Expect(err).To(gomega.Succeed())
+				gomega.Expect(*secret).Should(gomega.Equal(response.JwtSecret))
+			}()
+		}
+		wg.Wait()
+	})
+
+	ginkgo.It("should refresh the zone secrets that are about to expire in background", func() {
+		gomega.Expect(secretsManager.Close()).To(gomega.Succeed())
+		secretsManager = newManager(time.Second, WithRefreshAhead(600*time.Millisecond))
+		first := &grpc_jwt_go.SecretResponse{JwtSecret: "first"}
+		second := &grpc_jwt_go.SecretResponse{JwtSecret: "second"}
+		gomock.InOrder(
+			secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(first, nil),
+			secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(second, nil),
+		)
+		secret, err := secretsManager.GetZoneSecret("zone")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(*secret).Should(gomega.Equal(first.JwtSecret))
+
+		clock.Advance(500 * time.Millisecond)
+		// the cached secret is returned while it is renewed
+		secret, err = secretsManager.GetZoneSecret("zone")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(*secret).Should(gomega.Equal(first.JwtSecret))
+		gomega.Eventually(func() string {
+			secret, _ := secretsManager.GetZoneSecret("zone")
+			return *secret
+		}).Should(gomega.Equal(second.JwtSecret))
+	})
+
+	ginkgo.It("should serve the last known zone secret during the grace period", func() {
+		gomega.Expect(secretsManager.Close()).To(gomega.Succeed())
+		secretsManager = newManager(time.Second, WithStaleGracePeriod(time.Minute))
+		response := &grpc_jwt_go.SecretResponse{JwtSecret: "zoneSecret"}
+		release := make(chan struct{})
+		gomock.InOrder(
+			secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(response, nil),
+			secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, request *grpc_jwt_go.GetSecretRequest, opts ...grpc.CallOption) (*grpc_jwt_go.SecretResponse, error) {
+					<-release
+					return nil, nerrors.NewUnavailableError("unavailable").ToGRPC()
+				}),
+		)
+		_, err := secretsManager.GetZoneSecret("zone")
+		gomega.Expect(err).To(gomega.Succeed())
+		clock.Advance(1100 * time.Millisecond)
+		secretsManager.Evict()
+		// the stale secret is returned without waiting for its renewal
+		secret, err := secretsManager.GetZoneSecret("zone")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(*secret).Should(gomega.Equal(response.JwtSecret))
+		close(release)
+
+		clock.Advance(time.Minute)
+		secretsManager.Evict()
+		gomega.Expect(secretsManager.SecretCache).ShouldNot(gomega.HaveKey("zone"))
+	})
+
+	ginkgo.It("should not renew the zone secrets in background during the backoff of a failed renewal", func() {
+		gomega.Expect(secretsManager.Close()).To(gomega.Succeed())
+		secretsManager = newManager(time.Second, WithStaleGracePeriod(time.Minute), WithRefreshBackoff(10*time.Second))
+		first := &grpc_jwt_go.SecretResponse{JwtSecret: "first"}
+		second := &grpc_jwt_go.SecretResponse{JwtSecret: "second"}
+		gomock.InOrder(
+			secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(first, nil),
+			secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nerrors.NewUnavailableError("unavailable").ToGRPC()),
+			secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(second, nil),
+		)
+		_, err := secretsManager.GetZoneSecret("zone")
+		gomega.Expect(err).To(gomega.Succeed())
+		clock.Advance(1100 * time.Millisecond)
+		_, err = secretsManager.GetZoneSecret("zone")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Eventually(func() bool {
+			secretsManager.RLock()
+			defer secretsManager.RUnlock()
+			_, failed := secretsManager.refreshFailures["zone"]
+			return failed
+		}).Should(gomega.BeTrue())
+
+		// the requests during the backoff do not call the secrets service
+		clock.Advance(5 * time.Second)
+		gomega.Consistently(func() string {
+			secret, _ := secretsManager.GetZoneSecret("zone")
+			return *secret
+		}, 50*time.Millisecond).Should(gomega.Equal(first.JwtSecret))
+
+		clock.Advance(5 * time.Second)
+		gomega.Eventually(func() string {
+			secret, _ := secretsManager.GetZoneSecret("zone")
+			return *secret
+		}).Should(gomega.Equal(second.JwtSecret))
+	})
+
+	ginkgo.It("should not serve the last known zone secret if the secrets service rejects the request", func() {
+		gomega.Expect(secretsManager.Close()).To(gomega.Succeed())
+		secretsManager = newManager(time.Second, WithStaleGracePeriod(time.Minute))
+		response := &grpc_jwt_go.SecretResponse{JwtSecret: "zoneSecret"}
+		gomock.InOrder(
+			secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(response, nil),
+			secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nerrors.NewNotFoundError("zone not found").ToGRPC()).MinTimes(1),
+		)
+		_, err := secretsManager.GetZoneSecret("zone")
+		gomega.Expect(err).To(gomega.Succeed())
+		clock.Advance(1100 * time.Millisecond)
+		// the stale secret is dropped once the secrets service rejects its renewal
+		gomega.Eventually(func() nerrors.ErrorCode {
+			_, err := secretsManager.GetZoneSecret("zone")
+			if err != nil {
+				return nerrors.FromError(err).Code
+			}
+			return nerrors.OK
+		}).Should(gomega.Equal(nerrors.NotFound))
+	})
+
+	ginkgo.It("should validate the settings of the manager", func() {
+		_, err := NewZoneSecretManager(context.Background(), jwtConfig, secretsClientMock, 0)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = NewZoneSecretManager(context.Background(), jwtConfig, secretsClientMock, time.Second, WithRefreshAhead(time.Minute))
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = NewZoneSecretManager(context.Background(), jwtConfig, nil, time.Second)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should stop the eviction loop when the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		manager, err := NewZoneSecretManager(ctx, jwtConfig, secretsClientMock, time.Second)
+		gomega.Expect(err).To(gomega.Succeed())
+		cancel()
+		gomega.Eventually(manager.done).Should(gomega.BeClosed())
+		gomega.Expect(manager.Close()).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should abort and wait for the background retrievals when it is closed", func() {
+		var finished atomic.Bool
+		secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, request *grpc_jwt_go.GetSecretRequest, opts ...grpc.CallOption) (*grpc_jwt_go.SecretResponse, error) {
+				<-ctx.Done()
+				finished.Store(true)
+				return nil, ctx.Err()
+			}).Times(1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := secretsManager.GetZoneSecretWithContext(ctx, "zone")
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.DeadlineExceeded))
+
+		gomega.Expect(secretsManager.Close()).To(gomega.Succeed())
+		gomega.Expect(finished.Load()).Should(gomega.BeTrue())
+		_, err = secretsManager.GetZoneSecret("other")
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Canceled))
+	})
+
+	ginkgo.It("should return when the context of the request is done", func() {
+		response := &grpc_jwt_go.SecretResponse{JwtSecret: "zoneSecret"}
+		secretsClientMock.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, request *grpc_jwt_go.GetSecretRequest, opts ...grpc.CallOption) (*grpc_jwt_go.SecretResponse, error) {
+				time.Sleep(300 * time.Millisecond)
+				return response, ctx.Err()
+			}).Times(1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := secretsManager.GetZoneSecretWithContext(ctx, "zone")
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.DeadlineExceeded))
+
+		// the shared retrieval is not cancelled and the secret is cached
+		gomega.Eventually(func() error {
+			_, err := secretsManager.GetZoneSecretWithContext(context.Background(), "zone")
+			return err
+		}).Should(gomega.Succeed())
+	})
+
 })
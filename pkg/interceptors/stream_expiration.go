@@ -0,0 +1,236 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/config"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// streamSessionContextKey is the key used to store the stream session in the context.
+type streamSessionContextKey struct{}
+
+// streamSession tracks the expiration of the token of a stream, cancelling the context of the stream when
+// the token expires.
+type streamSession struct {
+	sync.Mutex
+	// authenticate verifies a new token sent by the client.
+	authenticate func(ctx context.Context) (context.Context, error)
+	// header with the metadata key used to build the context of the new tokens.
+	header string
+	// leeway with the clock skew tolerated when checking the expiration.
+	leeway time.Duration
+	// clock used to compute the remaining time of the tokens and to schedule the expiration.
+	clock njwt.Clock
+	// claim with the last verified claim.
+	claim *njwt.Claim
+	// cancel the context of the stream.
+	cancel context.CancelFunc
+	// timer that cancels the context when the token expires.
+	timer njwt.Timer
+	// generation of the timer, increased on each schedule so that a timer of a previous claim that has already
+	// fired does not cancel the context.
+	generation uint64
+	// expired is set once the context has been cancelled due to the expiration of the token.
+	expired bool
+}
+
+// serveStream authenticates a stream and calls the handler. If the expiration of the streams is enforced,
+// the context received by the handler is cancelled when the token expires, and the stream fails as
+// Unauthenticated unless the client provides a new token through RefreshStreamToken.
+func serveStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+	config config.JWTConfig, opts *options, authorize authorizeFunc) error {
+
+	newCtx, err := authenticate(stream.Context(), info.FullMethod, config, opts, authorize)
+	if err != nil {
+		return err
+	}
+	claim, ok := ClaimFromContext(newCtx)
+	if !opts.enforceStreamExpiration || !ok {
+		// uses this new context in the stream wrapper
+		w := newStreamContextWrapper(stream)
+		w.SetContext(newCtx)
+		return handler(srv, w)
+	}
+
+	session := &streamSession{
+		authenticate: func(ctx context.Context) (context.Context, error) {
+			return authenticate(ctx, info.FullMethod, config, opts, authorize)
+		},
+		header: config.Header,
+		leeway: opts.streamLeeway(),
+		clock:  opts.currentClock(),
+	}
+	sessionCtx, cancel := context.WithCancel(context.WithValue(newCtx, streamSessionContextKey{}, session))
+	session.start(claim, cancel)
+	defer session.stop()
+
+	w := newStreamContextWrapper(stream)
+	w.SetContext(sessionCtx)
+	err = handler(srv, &sessionStream{StreamContextWrapper: w, session: session})
+	if session.isExpired() {
+		return nerrors.NewUnauthenticatedError("token of the stream has expired").ToGRPC()
+	}
+	return err
+}
+
+// sessionStream is a stream whose messages are rejected once the token of its session has expired.
+type sessionStream struct {
+	StreamContextWrapper
+	session *streamSession
+}
+
+// SendMsg sends a message unless the token of the stream has expired.
+func (s *sessionStream) SendMsg(m interface{}) error {
+	if s.session.isExpired() {
+		return nerrors.NewUnauthenticatedError("token of the stream has expired").ToGRPC()
+	}
+	return s.StreamContextWrapper.SendMsg(m)
+}
+
+// RecvMsg receives a message unless the token of the stream has expired before or while waiting for it.
+func (s *sessionStream) RecvMsg(m interface{}) error {
+	if s.session.isExpired() {
+		return nerrors.NewUnauthenticatedError("token of the stream has expired").ToGRPC()
+	}
+	err := s.StreamContextWrapper.RecvMsg(m)
+	if s.session.isExpired() {
+		return nerrors.NewUnauthenticatedError("token of the stream has expired").ToGRPC()
+	}
+	return err
+}
+
+// start tracking the expiration of the claim.
+func (ss *streamSession) start(claim *njwt.Claim, cancel context.CancelFunc) {
+	ss.Lock()
+	defer ss.Unlock()
+	ss.cancel = cancel
+	ss.schedule(claim)
+}
+
+// schedule the cancellation of the context at the expiration of the claim. The caller must hold the lock.
+func (ss *streamSession) schedule(claim *njwt.Claim) {
+	ss.claim = claim
+	ss.generation++
+	if ss.timer != nil {
+		ss.timer.Stop()
+		ss.timer = nil
+	}
+	if claim.ExpiresAt == 0 {
+		return
+	}
+	generation := ss.generation
+	remaining := time.Unix(claim.ExpiresAt, 0).Add(ss.leeway).Sub(ss.clock.Now())
+	ss.timer = njwt.AfterFunc(ss.clock, remaining, func() {
+		ss.expire(generation)
+	})
+}
+
+// expire cancels the context of the stream, unless the claim has been replaced since the timer of the given
+// generation was scheduled.
+func (ss *streamSession) expire(generation uint64) {
+	ss.Lock()
+	defer ss.Unlock()
+	if generation != ss.generation {
+		return
+	}
+	log.Debug().Str("jwt_id", ss.claim.Id).Msg("token of the stream has expired, cancelling it")
+	ss.expired = true
+	ss.cancel()
+}
+
+// isExpired checks if the context has been cancelled due to the expiration of the token.
+func (ss *streamSession) isExpired() bool {
+	ss.Lock()
+	defer ss.Unlock()
+	return ss.expired
+}
+
+// stop tracking the expiration of the token, releasing the resources of the session.
+func (ss *streamSession) stop() {
+	ss.Lock()
+	defer ss.Unlock()
+	if ss.timer != nil {
+		ss.timer.Stop()
+	}
+	ss.cancel()
+}
+
+// refresh verifies a new token of the same user and extends the stream until its expiration. The token is
+// verified with the context of the stream, so that it is bound to its cancellation and values.
+func (ss *streamSession) refresh(ctx context.Context, token string) error {
+	newCtx, err := ss.authenticate(metadata.NewIncomingContext(ctx, metadata.Pairs(ss.header, token)))
+	if err != nil {
+		return err
+	}
+	claim, ok := ClaimFromContext(newCtx)
+	if !ok {
+		return nerrors.NewUnauthenticatedError("no claim found in the new token")
+	}
+	ss.Lock()
+	defer ss.Unlock()
+	if ss.expired {
+		return nerrors.NewUnauthenticatedError("token of the stream has already expired")
+	}
+	if claim.GetAuthxClaim().UserID != ss.claim.GetAuthxClaim().UserID {
+		return nerrors.NewPermissionDeniedError("new token belongs to a different user")
+	}
+	ss.schedule(claim)
+	return nil
+}
+
+// RefreshStreamToken extends a stream whose expiration is enforced with a new token of the same user. The
+// token is verified with the same checks applied when the stream was opened. Handlers call it with the tokens
+// sent by the client through the stream, for example, in a dedicated message.
+// Example:
+//
+//	if msg.Token != "" {
+//		if err := interceptors.RefreshStreamToken(stream.Context(), msg.Token); err != nil {
+//			return err
+//		}
+//	}
+func RefreshStreamToken(ctx context.Context, token string) error {
+	session, ok := ctx.Value(streamSessionContextKey{}).(*streamSession)
+	if !ok {
+		return nerrors.NewFailedPreconditionError("expiration is not enforced for this stream").ToGRPC()
+	}
+	if err := session.refresh(ctx, token); err != nil {
+		return nerrors.FromError(err).ToGRPC()
+	}
+	return nil
+}
+
+// StreamClaimFromContext returns the last verified claim of a stream whose expiration is enforced, including
+// the claims of the tokens sent through RefreshStreamToken. For other streams, the claim of the context is
+// returned.
+func StreamClaimFromContext(ctx context.Context) (*njwt.Claim, bool) {
+	session, ok := ctx.Value(streamSessionContextKey{}).(*streamSession)
+	if !ok {
+		return ClaimFromContext(ctx)
+	}
+	session.Lock()
+	defer session.Unlock()
+	return session.claim, true
+}
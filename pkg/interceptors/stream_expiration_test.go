@@ -0,0 +1,134 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+)
+
+// testServerStream is a grpc.ServerStream that only provides the context.
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (tss *testServerStream) Context() context.Context {
+	return tss.ctx
+}
+
+var _ = ginkgo.Describe("Stream expiration", func() {
+
+	config := GetTestJWTConfig()
+	info := &grpc.StreamServerInfo{FullMethod: "/ping.PingService/Watch"}
+	var clock *njwt.FakeClock
+	var interceptor grpc.StreamServerInterceptor
+
+	ginkgo.BeforeEach(func() {
+		clock = njwt.NewFakeClock(time.Now())
+		interceptor = JwtStreamInterceptor(config, WithStreamExpiration(), WithClock(clock))
+	})
+
+	generateToken := func(authClaim *njwt.AuthxClaim, expiration time.Duration) string {
+		token, err := njwt.New().Generate(njwt.NewClaimWithClock(clock, "authx", expiration, authClaim), config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+		return *token
+	}
+	openStream := func(token string, handler grpc.StreamHandler) error {
+		ctx, cancel := CreateTestIncomingContext(config.Header, token)
+		defer cancel()
+		return interceptor(nil, &testServerStream{ctx: ctx}, info, handler)
+	}
+
+	ginkgo.It("should cancel the stream when the token expires", func() {
+		err := openStream(generateToken(GetTestAuthxClaim(), time.Minute), func(srv interface{}, stream grpc.ServerStream) error {
+			gomega.Consistently(stream.Context().Done(), 50*time.Millisecond).ShouldNot(gomega.BeClosed())
+			clock.Advance(2 * time.Minute)
+			gomega.Eventually(stream.Context().Done()).Should(gomega.BeClosed())
+			return stream.Context().Err()
+		})
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should reject the messages of the stream once the token expires", func() {
+		err := openStream(generateToken(GetTestAuthxClaim(), time.Minute), func(srv interface{}, stream grpc.ServerStream) error {
+			clock.Advance(2 * time.Minute)
+			gomega.Eventually(stream.Context().Done()).Should(gomega.BeClosed())
+			sendErr := stream.SendMsg(nil)
+			gomega.Expect(nerrors.FromGRPC(sendErr).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+			recvErr := stream.RecvMsg(nil)
+			gomega.Expect(nerrors.FromGRPC(recvErr).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+			return nil
+		})
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should extend the stream with a refreshed token", func() {
+		authClaim := GetTestAuthxClaim()
+		err := openStream(generateToken(authClaim, time.Minute), func(srv interface{}, stream grpc.ServerStream) error {
+			if err := RefreshStreamToken(stream.Context(), generateToken(authClaim, time.Hour)); err != nil {
+				return err
+			}
+			claim, ok := StreamClaimFromContext(stream.Context())
+			gomega.Expect(ok).Should(gomega.BeTrue())
+			gomega.Expect(claim.ExpiresAt).Should(gomega.Equal(clock.Now().Add(time.Hour).Unix()))
+			clock.Advance(2 * time.Minute)
+			gomega.Consistently(stream.Context().Done(), 50*time.Millisecond).ShouldNot(gomega.BeClosed())
+			clock.Advance(time.Hour)
+			gomega.Eventually(stream.Context().Done()).Should(gomega.BeClosed())
+			return nil
+		})
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should not cancel the stream with the timer of a replaced token", func() {
+		authClaim := GetTestAuthxClaim()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		session := &streamSession{clock: clock}
+		session.start(njwt.NewClaimWithClock(clock, "authx", time.Minute, authClaim), cancel)
+
+		// the token is refreshed exactly when the timer of the old one fires
+		session.Lock()
+		clock.Advance(time.Minute)
+		session.schedule(njwt.NewClaimWithClock(clock, "authx", time.Hour, authClaim))
+		session.Unlock()
+
+		gomega.Consistently(ctx.Done(), 50*time.Millisecond).ShouldNot(gomega.BeClosed())
+		gomega.Expect(session.isExpired()).Should(gomega.BeFalse())
+		clock.Advance(time.Hour)
+		gomega.Eventually(ctx.Done()).Should(gomega.BeClosed())
+		gomega.Expect(session.isExpired()).Should(gomega.BeTrue())
+	})
+
+	ginkgo.It("should reject the tokens of other users", func() {
+		err := openStream(generateToken(GetTestAuthxClaim(), time.Hour), func(srv interface{}, stream grpc.ServerStream) error {
+			return RefreshStreamToken(stream.Context(), generateToken(GetTestAuthxClaim(), time.Hour))
+		})
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.PermissionDenied))
+
+		err = RefreshStreamToken(context.Background(), generateToken(GetTestAuthxClaim(), time.Hour))
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.FailedPrecondition))
+	})
+})
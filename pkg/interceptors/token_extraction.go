@@ -0,0 +1,117 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/config"
+	"google.golang.org/grpc/metadata"
+)
+
+// bearerScheme is the authentication scheme accepted by the interceptors and the HTTP middlewares if the
+// configuration does not define one.
+const bearerScheme = "Bearer"
+
+// tokenFromContext returns the token of the incoming metadata of a context.
+func tokenFromContext(ctx context.Context, config config.JWTConfig) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nerrors.NewUnauthenticatedError("retrieving metadata failed")
+	}
+	return tokenFromMetadata(md, config)
+}
+
+// tokenFromMetadata returns the token found in the headers of the configuration.
+func tokenFromMetadata(md metadata.MD, config config.JWTConfig) (string, error) {
+	found := false
+	values := make([]string, 0)
+	for _, header := range config.TokenHeaders() {
+		if headerValues, exists := md[header]; exists {
+			found = true
+			values = append(values, headerValues...)
+		}
+	}
+	if !found {
+		return "", nerrors.NewUnauthenticatedError("no auth details supplied")
+	}
+	return selectToken(values, acceptedScheme(config))
+}
+
+// tokenFromHeader returns the token found in the headers of an HTTP request.
+func tokenFromHeader(header http.Header, config config.JWTConfig) (string, error) {
+	values := make([]string, 0)
+	for _, name := range config.TokenHeaders() {
+		values = append(values, header.Values(name)...)
+	}
+	return selectToken(values, acceptedScheme(config))
+}
+
+// acceptedScheme returns the authentication scheme that prefixes the tokens. The Bearer scheme is accepted if
+// the configuration does not define one.
+func acceptedScheme(config config.JWTConfig) string {
+	if config.Scheme == "" {
+		return bearerScheme
+	}
+	return config.Scheme
+}
+
+// selectToken returns the only token contained in a list of header values, removing the authentication scheme.
+// Empty values are ignored, and requests containing different tokens are rejected to avoid verifying one
+// token while the handlers use another one.
+func selectToken(values []string, scheme string) (string, error) {
+	token := ""
+	for _, value := range values {
+		current, err := removeScheme(strings.TrimSpace(value), scheme)
+		if err != nil {
+			return "", err
+		}
+		if current == "" || current == token {
+			continue
+		}
+		if token != "" {
+			return "", nerrors.NewUnauthenticatedError("request contains several conflicting tokens")
+		}
+		token = current
+	}
+	if token == "" {
+		return "", nerrors.NewNotFoundError("error getting token. Log in to the platform")
+	}
+	return token, nil
+}
+
+// removeScheme removes the authentication scheme of a header value. Values without scheme are returned as
+// they are, as tokens never contain spaces, while values with a different scheme are rejected.
+func removeScheme(value string, scheme string) (string, error) {
+	parts := strings.Fields(value)
+	switch len(parts) {
+	case 0:
+		return "", nil
+	case 1:
+		return parts[0], nil
+	case 2:
+		if scheme != "" && strings.EqualFold(parts[0], scheme) {
+			return parts[1], nil
+		}
+		return "", nerrors.NewUnauthenticatedError("unsupported authentication scheme [%s]", parts[0])
+	default:
+		return "", nerrors.NewUnauthenticatedError("invalid authorization header format")
+	}
+}
@@ -0,0 +1,91 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/config"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc/metadata"
+)
+
+var _ = ginkgo.Describe("Token extraction", func() {
+
+	cfg := config.JWTConfig{Secret: "secret", Header: "Authorization", Headers: []string{"x-token"}, Scheme: "Bearer"}
+
+	ginkgo.It("should remove the authentication scheme", func() {
+		for _, value := range []string{"Bearer abc", "bearer abc", "abc", " Bearer  abc "} {
+			token, err := tokenFromMetadata(metadata.Pairs("authorization", value), cfg)
+			gomega.Expect(err).Should(gomega.Succeed())
+			gomega.Expect(token).Should(gomega.Equal("abc"))
+		}
+		_, err := tokenFromMetadata(metadata.Pairs("authorization", "Basic abc"), cfg)
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should accept the Bearer scheme if the configuration does not define one", func() {
+		noScheme := config.JWTConfig{Secret: "secret", Header: "Authorization"}
+		for _, value := range []string{"Bearer abc", "abc"} {
+			token, err := tokenFromMetadata(metadata.Pairs("authorization", value), noScheme)
+			gomega.Expect(err).Should(gomega.Succeed())
+			gomega.Expect(token).Should(gomega.Equal("abc"))
+		}
+		_, err := tokenFromMetadata(metadata.Pairs("authorization", "Basic abc"), noScheme)
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should check the headers in order", func() {
+		token, err := tokenFromMetadata(metadata.Pairs("x-token", "abc"), cfg)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(token).Should(gomega.Equal("abc"))
+
+		token, err = tokenFromMetadata(metadata.Pairs("authorization", "", "x-token", "abc"), cfg)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(token).Should(gomega.Equal("abc"))
+
+		_, err = tokenFromMetadata(metadata.Pairs("other", "abc"), cfg)
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+		_, err = tokenFromMetadata(metadata.Pairs("authorization", ""), cfg)
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.NotFound))
+	})
+
+	ginkgo.It("should reject conflicting tokens", func() {
+		token, err := tokenFromMetadata(metadata.Pairs("authorization", "Bearer abc", "x-token", "abc"), cfg)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(token).Should(gomega.Equal("abc"))
+
+		_, err = tokenFromMetadata(metadata.Pairs("authorization", "abc", "authorization", "def"), cfg)
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+		_, err = tokenFromMetadata(metadata.Pairs("authorization", "abc", "x-token", "def"), cfg)
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should verify the tokens sent with the scheme", func() {
+		authClaim := GetTestAuthxClaim()
+		token, err := njwt.New().Generate(njwt.NewClaim("authx", time.Hour, authClaim), cfg.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", cfg.HeaderValue(*token)))
+		claim, err := authorizeJWTToken(ctx, cfg, newOptions())
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(claim.GetAuthxClaim().UserID).Should(gomega.Equal(authClaim.UserID))
+	})
+})
@@ -0,0 +1,114 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/zerolog/log"
+)
+
+// TokenSource defines the methods required to obtain the token attached to the outgoing requests.
+type TokenSource interface {
+	// Token returns the token to be sent to the server.
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc is an adapter to use ordinary functions as token sources.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token returns the token to be sent to the server.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticTokenSource returns a token source that always returns the same token.
+func StaticTokenSource(token string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// RefreshingTokenSource caches the token obtained from an underlying source, and asks for a new one when
+// the cached token is about to expire, or when it has been invalidated after being rejected by the server.
+type RefreshingTokenSource struct {
+	sync.Mutex
+	source TokenSource
+	margin time.Duration
+	token  string
+	// clock with the current time used to check the expiration of the cached token.
+	clock njwt.Clock
+}
+
+// NewRefreshingTokenSource creates a token source that refreshes the token of the underlying source
+// when it expires in less than the given margin.
+// Example:
+//
+//	source := interceptors.NewRefreshingTokenSource(interceptors.TokenSourceFunc(login), njwt.DefaultExpirationMargin)
+//	conn, err := grpc.Dial(address, interceptors.WithClientJWTInterceptor(cfg, source))
+func NewRefreshingTokenSource(source TokenSource, margin time.Duration) *RefreshingTokenSource {
+	return NewRefreshingTokenSourceWithClock(source, margin, njwt.SystemClock)
+}
+
+// NewRefreshingTokenSourceWithClock creates a token source like NewRefreshingTokenSource that checks the
+// expiration of the cached token with the given clock.
+func NewRefreshingTokenSourceWithClock(source TokenSource, margin time.Duration, clock njwt.Clock) *RefreshingTokenSource {
+	if clock == nil {
+		clock = njwt.SystemClock
+	}
+	return &RefreshingTokenSource{
+		source: source,
+		margin: margin,
+		clock:  clock,
+	}
+}
+
+// Token returns the cached token, refreshing it if required.
+func (rts *RefreshingTokenSource) Token(ctx context.Context) (string, error) {
+	rts.Lock()
+	defer rts.Unlock()
+	if rts.token != "" {
+		expired, err := njwt.IsTokenExpiredWithClock(rts.token, rts.clock, rts.margin)
+		if err == nil && !*expired {
+			return rts.token, nil
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("unable to check the expiration of the cached token, refreshing it")
+		}
+	}
+	token, err := rts.source.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	rts.token = token
+	return token, nil
+}
+
+// Invalidate the cached token so that a new one is obtained in the next call.
+func (rts *RefreshingTokenSource) Invalidate() {
+	rts.Lock()
+	defer rts.Unlock()
+	rts.token = ""
+}
+
+// invalidator defines the method implemented by the token sources whose cached token can be discarded.
+type invalidator interface {
+	Invalidate()
+}
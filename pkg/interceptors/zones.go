@@ -26,29 +26,25 @@ import (
 	"github.com/napptive/njwt/pkg/njwt"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/metadata"
 )
 
 // WithZoneAwareJWTInterceptor creates a gRPC interceptor that verifies if the JWT received is
 // valid attending to the zone that issued it.
-func WithZoneAwareJWTInterceptor(config config.JWTConfig, secretProvider SecretProvider) grpc.ServerOption {
-	return grpc.UnaryInterceptor(ZoneAwareJWTInterceptor(config, secretProvider))
+func WithZoneAwareJWTInterceptor(config config.JWTConfig, secretProvider SecretProvider, opts ...Option) grpc.ServerOption {
+	return grpc.UnaryInterceptor(ZoneAwareJWTInterceptor(config, secretProvider, opts...))
 }
 
 // ZoneAwareJWTInterceptor verifies the JWT token and adds the claim information in the context
-func ZoneAwareJWTInterceptor(config config.JWTConfig, secretProvider SecretProvider) grpc.UnaryServerInterceptor {
+func ZoneAwareJWTInterceptor(config config.JWTConfig, secretProvider SecretProvider, opts ...Option) grpc.UnaryServerInterceptor {
+	interceptorOpts := newOptions(opts...)
 	return func(ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler) (interface{}, error) {
 
-		claim, err := authorizeZoneAwareJWTToken(ctx, config, secretProvider)
-		if err != nil {
-			return nil, nerrors.FromError(err).ToGRPC()
-		}
-
-		// add the claim information to the context metadata
-		newCtx, err := AddClaimToContext(claim, ctx)
+		newCtx, err := authenticate(ctx, info.FullMethod, config, interceptorOpts, func(ctx context.Context) (*njwt.Claim, error) {
+			return authorizeZoneAwareJWTToken(ctx, config, secretProvider, interceptorOpts)
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -57,23 +53,18 @@ func ZoneAwareJWTInterceptor(config config.JWTConfig, secretProvider SecretProvi
 }
 
 // authorizeZoneAwareJWTToken checks the token and returns the authxClaim
-func authorizeZoneAwareJWTToken(ctx context.Context, config config.JWTConfig, secretProvider SecretProvider) (*njwt.Claim, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, nerrors.NewUnauthenticatedError("retrieving metadata failed")
-	}
-
-	token, ok := md[config.Header]
-	if !ok {
-		return nil, nerrors.NewUnauthenticatedError("no auth details supplied")
-	}
-	if token[0] == "" {
-		return nil, nerrors.NewNotFoundError("error getting token. Log in to the platform")
+func authorizeZoneAwareJWTToken(ctx context.Context, config config.JWTConfig, secretProvider SecretProvider, opts *options) (*njwt.Claim, error) {
+	token, err := tokenFromContext(ctx, config)
+	if err != nil {
+		return nil, err
 	}
 
 	// Check the token and get the authx claim
-	claim := &njwt.Claim{PersonalClaim: &njwt.AuthxClaim{}}
-	_, err := jwt.ParseWithClaims(token[0], claim, func(token *jwt.Token) (interface{}, error) {
+	claim, err := njwt.ParseWithKeyFunc(token, &njwt.AuthxClaim{}, func(token *jwt.Token) (interface{}, error) {
+		// Tokens signed with a key of the keyring are verified attending to their kid header.
+		if _, hasKeyID := token.Header[njwt.KeyIDHeader]; hasKeyID && opts.keyring != nil {
+			return njwt.KeyringKeyFunc(opts.keyring)(token)
+		}
 		// From https://github.com/golang-jwt/jwt security notice related to
 		// https://auth0.com/blog/critical-vulnerabilities-in-json-web-token-libraries/
 		// Don't forget to validate the alg is what you expect.
@@ -91,25 +82,16 @@ func authorizeZoneAwareJWTToken(ctx context.Context, config config.JWTConfig, se
 			log.Error().Str("token", token.Raw).Msg("token not generated by napptive, cannot extract personal claim")
 			return nil, nerrors.NewInternalError("invalid token")
 		}
-		secret, err := secretProvider.GetZoneSecret(pc.ZoneID)
+		secret, err := NewContextSecretProvider(secretProvider).GetZoneSecretWithContext(ctx, pc.ZoneID)
 		if err != nil {
 			log.Error().Err(err).Str("zone_id", pc.ZoneID).Msg("unable to retrieve secret associated with the given zone identifier.")
-			return nil, nerrors.NewInternalError("invalid token")
+			return nil, secretRetrievalError(err)
 		}
 		return []byte(*secret), nil
-	})
+	}, opts.validationOptions())
 
 	if err != nil {
-		castErr, ok := err.(*jwt.ValidationError)
-		if !ok {
-			return nil, nerrors.NewUnauthenticatedError("error recovering token [%s]", err.Error())
-		}
-		switch castErr.Errors {
-		case jwt.ValidationErrorExpired:
-			return nil, nerrors.NewUnauthenticatedError("[%s]. Please, log in to the platform again", err.Error())
-		default:
-			return nil, nerrors.NewUnauthenticatedError("error recovering token [%s]", err.Error())
-		}
+		return nil, toAuthenticationError(err)
 	}
 
 	return claim, nil
@@ -117,31 +99,20 @@ func authorizeZoneAwareJWTToken(ctx context.Context, config config.JWTConfig, se
 
 // WithZoneAwareJWTStreamInterceptor creates a gRPC stream interceptor that verifies if the JWT received is
 // // valid attending to the zone that issued it.
-func WithZoneAwareJWTStreamInterceptor(config config.JWTConfig, secretProvider SecretProvider) grpc.ServerOption {
-	return grpc.StreamInterceptor(ZoneAwareJWTStreamInterceptor(config, secretProvider))
+func WithZoneAwareJWTStreamInterceptor(config config.JWTConfig, secretProvider SecretProvider, opts ...Option) grpc.ServerOption {
+	return grpc.StreamInterceptor(ZoneAwareJWTStreamInterceptor(config, secretProvider, opts...))
 }
 
 // ZoneAwareJWTStreamInterceptor verifies the JWT token and adds the claim information in the context
-func ZoneAwareJWTStreamInterceptor(config config.JWTConfig, secretProvider SecretProvider) grpc.StreamServerInterceptor {
+func ZoneAwareJWTStreamInterceptor(config config.JWTConfig, secretProvider SecretProvider, opts ...Option) grpc.StreamServerInterceptor {
+	interceptorOpts := newOptions(opts...)
 	return func(srv interface{},
 		stream grpc.ServerStream,
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler) error {
 
-		ctx := stream.Context()
-		authClaim, err := authorizeZoneAwareJWTToken(ctx, config, secretProvider)
-		if err != nil {
-			return nerrors.FromError(err).ToGRPC()
-		}
-
-		// add the claim information to the context metadata
-		newCtx, err := AddClaimToContext(authClaim, ctx)
-		if err != nil {
-			return err
-		}
-		// uses this new context in the stream wrapper
-		w := newStreamContextWrapper(stream)
-		w.SetContext(newCtx)
-		return handler(srv, w)
+		return serveStream(srv, stream, info, handler, config, interceptorOpts, func(ctx context.Context) (*njwt.Claim, error) {
+			return authorizeZoneAwareJWTToken(ctx, config, secretProvider, interceptorOpts)
+		})
 	}
 }
@@ -18,13 +18,19 @@ package interceptors
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"net"
+	"net/http/httptest"
 	"time"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/golang/mock/gomock"
 	"github.com/napptive/grpc-ping-go"
 	"github.com/napptive/nerrors/pkg/nerrors"
 	"github.com/napptive/njwt/pkg/config"
+	"github.com/napptive/njwt/pkg/jwks"
 	"github.com/napptive/njwt/pkg/njwt"
 	"github.com/napptive/njwt/pkg/utils"
 	"github.com/onsi/ginkgo"
@@ -60,7 +66,7 @@ var _ = ginkgo.Describe("Zone Aware JWT interceptor", func() {
 		defer cancel()
 
 		secretProviderMock.EXPECT().GetZoneSecret(authClaim.ZoneID).Return(&config.Secret, nil)
-		recovered, err := authorizeZoneAwareJWTToken(ctx, config, secretProviderMock)
+		recovered, err := authorizeZoneAwareJWTToken(ctx, config, secretProviderMock, newOptions())
 		gomega.Expect(err).Should(gomega.Succeed())
 		gomega.Expect(recovered.GetAuthxClaim().UserID).Should(gomega.Equal(authClaim.UserID))
 		gomega.Expect(recovered.GetAuthxClaim().Username).Should(gomega.Equal(authClaim.Username))
@@ -81,11 +87,96 @@ var _ = ginkgo.Describe("Zone Aware JWT interceptor", func() {
 		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
 		defer cancel()
 		secretProviderMock.EXPECT().GetZoneSecret(authClaim.ZoneID).Return(&config.Secret, nil)
-		_, err = authorizeZoneAwareJWTToken(ctx, config, secretProviderMock)
+		_, err = authorizeZoneAwareJWTToken(ctx, config, secretProviderMock, newOptions())
 		gomega.Expect(err).ShouldNot(gomega.Succeed())
 
 	})
 
+	ginkgo.It("check the context of the request is passed to the secret provider", func() {
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim(utils.GetTestUserId(), time.Hour, &authClaim)
+		config := GetTestJWTConfig()
+
+		token, err := njwt.New().Generate(claim, config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+		contextProviderMock := NewMockContextSecretProvider(ctrl)
+		contextProviderMock.EXPECT().GetZoneSecretWithContext(ctx, authClaim.ZoneID).Return(&config.Secret, nil)
+		_, err = authorizeZoneAwareJWTToken(ctx, config, NewSecretProvider(contextProviderMock), newOptions())
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		// providers without context are not called once the request is cancelled
+		cancel()
+		_, err = authorizeZoneAwareJWTToken(ctx, config, secretProviderMock, newOptions())
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Canceled))
+	})
+
+	ginkgo.It("check the errors of the secret provider not caused by the token are returned", func() {
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim(utils.GetTestUserId(), time.Hour, &authClaim)
+		config := GetTestJWTConfig()
+
+		token, err := njwt.New().Generate(claim, config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+		secretProviderMock.EXPECT().GetZoneSecret(authClaim.ZoneID).Return(nil, nerrors.NewUnavailableError("secrets service unavailable"))
+		_, err = authorizeZoneAwareJWTToken(ctx, config, secretProviderMock, newOptions())
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unavailable))
+
+		secretProviderMock.EXPECT().GetZoneSecret(authClaim.ZoneID).Return(nil, nerrors.NewNotFoundError("zone not found").ToGRPC())
+		_, err = authorizeZoneAwareJWTToken(ctx, config, secretProviderMock, newOptions())
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("check JWT Token is rejected if the issuer is not accepted", func() {
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim("other", time.Duration(1)*time.Hour, &authClaim)
+		config := GetTestJWTConfig()
+
+		token, err := njwt.New().Generate(claim, config.Secret)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+		secretProviderMock.EXPECT().GetZoneSecret(authClaim.ZoneID).Return(&config.Secret, nil)
+		opts := newOptions(WithValidationOptions(&njwt.ValidationOptions{Issuers: []string{"authx"}}))
+		_, err = authorizeZoneAwareJWTToken(ctx, config, secretProviderMock, opts)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("check JWT Token is verified with a remote JWK set", func() {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		gomega.Expect(err).Should(gomega.Succeed())
+		signingKey, err := njwt.NewSigningKey("zone-key", jwt.SigningMethodES256, privateKey)
+		gomega.Expect(err).Should(gomega.Succeed())
+		keyring, err := njwt.NewMemoryKeyring(signingKey)
+		gomega.Expect(err).Should(gomega.Succeed())
+		server := httptest.NewServer(jwks.Handler(keyring))
+		defer server.Close()
+
+		authClaim := GetTestAuthxClaim()
+		claim := njwt.NewClaim(utils.GetTestUserId(), time.Duration(1)*time.Hour, &authClaim)
+		tokenMgr, err := njwt.NewWithKeyring(keyring)
+		gomega.Expect(err).Should(gomega.Succeed())
+		token, err := tokenMgr.Generate(claim)
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		config := GetTestJWTConfig()
+		ctx, cancel := CreateTestIncomingContext(config.Header, *token)
+		defer cancel()
+		// The secret provider is not expected to be called for tokens with a kid header.
+		opts := newOptions(WithKeyring(jwks.NewRemoteKeyring(server.URL, time.Hour)))
+		recovered, err := authorizeZoneAwareJWTToken(ctx, config, secretProviderMock, opts)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(recovered.GetAuthxClaim().UserID).Should(gomega.Equal(authClaim.UserID))
+	})
+
 })
 
 type defaultSecretProvider struct {
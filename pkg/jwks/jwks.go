@@ -0,0 +1,239 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// KeyUseSignature with the value of the use parameter for signature keys.
+	KeyUseSignature = "sig"
+	// ContentType with the media type of a JWK set document.
+	ContentType = "application/jwk-set+json"
+)
+
+// JSONWebKey with the public information of a key as defined in RFC 7517.
+type JSONWebKey struct {
+	// Kty with the key type: RSA, EC or OKP.
+	Kty string `json:"kty"`
+	// Kid with the key identifier.
+	Kid string `json:"kid,omitempty"`
+	// Use with the intended use of the key.
+	Use string `json:"use,omitempty"`
+	// Alg with the algorithm of the key.
+	Alg string `json:"alg,omitempty"`
+	// N with the modulus of an RSA key.
+	N string `json:"n,omitempty"`
+	// E with the exponent of an RSA key.
+	E string `json:"e,omitempty"`
+	// Crv with the curve of an EC or OKP key.
+	Crv string `json:"crv,omitempty"`
+	// X with the x coordinate of an EC key, or the public key of an OKP key.
+	X string `json:"x,omitempty"`
+	// Y with the y coordinate of an EC key.
+	Y string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet with a set of keys as defined in RFC 7517.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// FromKeys creates a JWK set with the public part of the given keys. Keys using HMAC algorithms are
+// never published as they are shared secrets.
+func FromKeys(keys []*njwt.Key) (*JSONWebKeySet, error) {
+	result := &JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		if _, isHMAC := key.Method.(*jwt.SigningMethodHMAC); isHMAC {
+			continue
+		}
+		jwk, err := NewJSONWebKey(key)
+		if err != nil {
+			return nil, err
+		}
+		result.Keys = append(result.Keys, *jwk)
+	}
+	return result, nil
+}
+
+// FromKeyring creates a JWK set with the public part of the verification keys of a keyring.
+func FromKeyring(keyring njwt.Keyring) (*JSONWebKeySet, error) {
+	keys, err := keyring.VerificationKeys()
+	if err != nil {
+		return nil, err
+	}
+	return FromKeys(keys)
+}
+
+// NewJSONWebKey creates the JWK representation of the public part of a key.
+func NewJSONWebKey(key *njwt.Key) (*JSONWebKey, error) {
+	jwk := &JSONWebKey{
+		Kid: key.ID,
+		Use: KeyUseSignature,
+		Alg: key.Method.Alg(),
+	}
+	switch publicKey := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encode(publicKey.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = encode(publicKey.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(publicKey.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encode(publicKey)
+	default:
+		return nil, nerrors.NewInvalidArgumentError("unsupported key type for algorithm %s", key.Method.Alg())
+	}
+	return jwk, nil
+}
+
+// ToKey transforms the JWK into a verification key.
+func (jwk *JSONWebKey) ToKey() (*njwt.Key, error) {
+	var publicKey interface{}
+	defaultAlg := ""
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		defaultAlg = jwt.SigningMethodRS256.Alg()
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve, defaultAlg = elliptic.P256(), jwt.SigningMethodES256.Alg()
+		case "P-384":
+			curve, defaultAlg = elliptic.P384(), jwt.SigningMethodES384.Alg()
+		case "P-521":
+			curve, defaultAlg = elliptic.P521(), jwt.SigningMethodES512.Alg()
+		default:
+			return nil, nerrors.NewInvalidArgumentError("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, nerrors.NewInvalidArgumentError("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = ed25519.PublicKey(x)
+		defaultAlg = jwt.SigningMethodEdDSA.Alg()
+	default:
+		return nil, nerrors.NewInvalidArgumentError("unsupported key type %s", jwk.Kty)
+	}
+	alg := jwk.Alg
+	if alg == "" {
+		alg = defaultAlg
+	}
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, nerrors.NewInvalidArgumentError("unsupported algorithm %s", alg)
+	}
+	return njwt.NewVerificationKey(jwk.Kid, method, publicKey)
+}
+
+// ToKeys transforms the JWK set into a list of verification keys. Keys that are not intended to be used
+// for signatures are ignored, and keys that are not supported are skipped so that the rest of the set can
+// still be used.
+func (set *JSONWebKeySet) ToKeys() ([]*njwt.Key, error) {
+	keys := make([]*njwt.Key, 0, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != KeyUseSignature {
+			continue
+		}
+		key, err := jwk.ToKey()
+		if err != nil {
+			log.Warn().Err(err).Str("kid", jwk.Kid).Str("kty", jwk.Kty).Msg("skipping unsupported JWK")
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Handler returns an HTTP handler that serves the JWK set of the verification keys of a keyring.
+// Example:
+//
+//	http.Handle("/.well-known/jwks.json", jwks.Handler(keyring))
+func Handler(keyring njwt.Keyring) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		set, err := FromKeyring(keyring)
+		if err != nil {
+			log.Error().Err(err).Msg("unable to build JWK set")
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		if err := json.NewEncoder(w).Encode(set); err != nil {
+			log.Error().Err(err).Msg("unable to write JWK set")
+		}
+	})
+}
+
+// encode a value using the base64url encoding without padding.
+func encode(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(value)
+}
+
+// decode a value encoded with the base64url encoding without padding.
+func decode(value string) ([]byte, error) {
+	result, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, nerrors.NewInvalidArgumentErrorFrom(err, "invalid key parameter")
+	}
+	return result, nil
+}
@@ -0,0 +1,234 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// getTestKeys returns a set of signing keys with all the supported key types.
+func getTestKeys() []*njwt.Key {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	gomega.Expect(err).To(gomega.Succeed())
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	gomega.Expect(err).To(gomega.Succeed())
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	gomega.Expect(err).To(gomega.Succeed())
+
+	rsaSigningKey, err := njwt.NewSigningKey("rsa", jwt.SigningMethodRS256, rsaKey)
+	gomega.Expect(err).To(gomega.Succeed())
+	ecSigningKey, err := njwt.NewSigningKey("ec", jwt.SigningMethodES256, ecKey)
+	gomega.Expect(err).To(gomega.Succeed())
+	edSigningKey, err := njwt.NewSigningKey("ed", jwt.SigningMethodEdDSA, edKey)
+	gomega.Expect(err).To(gomega.Succeed())
+	return []*njwt.Key{rsaSigningKey, ecSigningKey, edSigningKey}
+}
+
+var _ = ginkgo.Describe("JWKS publishing tests", func() {
+
+	ginkgo.It("should be able to transform keys into a JWK set and back", func() {
+		keys := getTestKeys()
+		set, err := FromKeys(append(keys, njwt.NewHMACKey("hmac", "secret")))
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(set.Keys).To(gomega.HaveLen(len(keys)))
+
+		recovered, err := set.ToKeys()
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(recovered).To(gomega.HaveLen(len(keys)))
+		for index, key := range recovered {
+			gomega.Expect(key.ID).To(gomega.Equal(keys[index].ID))
+			gomega.Expect(key.Method).To(gomega.Equal(keys[index].Method))
+			gomega.Expect(key.Public).To(gomega.Equal(keys[index].Public))
+			gomega.Expect(key.CanSign()).To(gomega.BeFalse())
+		}
+	})
+
+	ginkgo.It("should serve the JWK set of a keyring", func() {
+		keys := getTestKeys()
+		keyring, err := njwt.NewMemoryKeyring(keys[0], keys[1:]...)
+		gomega.Expect(err).To(gomega.Succeed())
+		server := httptest.NewServer(Handler(keyring))
+		defer server.Close()
+
+		response, err := http.Get(server.URL)
+		gomega.Expect(err).To(gomega.Succeed())
+		defer response.Body.Close()
+		gomega.Expect(response.StatusCode).To(gomega.Equal(http.StatusOK))
+		gomega.Expect(response.Header.Get("Content-Type")).To(gomega.Equal(ContentType))
+		set := &JSONWebKeySet{}
+		gomega.Expect(json.NewDecoder(response.Body).Decode(set)).To(gomega.Succeed())
+		gomega.Expect(set.Keys).To(gomega.HaveLen(len(keys)))
+		for _, jwk := range set.Keys {
+			gomega.Expect(jwk.Kid).ShouldNot(gomega.BeEmpty())
+			gomega.Expect(jwk.Use).Should(gomega.Equal(KeyUseSignature))
+		}
+	})
+
+	ginkgo.It("should verify tokens with the keys of a remote JWK set", func() {
+		keys := getTestKeys()
+		keyring, err := njwt.NewMemoryKeyring(keys[1])
+		gomega.Expect(err).To(gomega.Succeed())
+		server := httptest.NewServer(Handler(keyring))
+		defer server.Close()
+
+		signer, err := njwt.NewWithKeyring(keyring)
+		gomega.Expect(err).To(gomega.Succeed())
+		token, err := signer.Generate(njwt.NewClaim("tt", time.Hour, nil))
+		gomega.Expect(err).To(gomega.Succeed())
+
+		remote := NewRemoteKeyring(server.URL, time.Hour)
+		verifier, err := njwt.NewWithKeyring(remote)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = verifier.Recover(*token, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		// Keys published after the first retrieval are discovered by their identifier.
+		gomega.Expect(keyring.Rotate(keys[2])).To(gomega.Succeed())
+		token, err = signer.Generate(njwt.NewClaim("tt", time.Hour, nil))
+		gomega.Expect(err).To(gomega.Succeed())
+		remote.minRefreshInterval = 0
+		_, err = verifier.Recover(*token, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		_, err = remote.SigningKey()
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should keep the cached keys if the remote JWK set is unavailable", func() {
+		keys := getTestKeys()
+		keyring, err := njwt.NewMemoryKeyring(keys[0])
+		gomega.Expect(err).To(gomega.Succeed())
+		available := true
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !available {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			Handler(keyring).ServeHTTP(w, r)
+		}))
+		defer server.Close()
+
+		remote := NewRemoteKeyring(server.URL, time.Hour)
+		gomega.Expect(remote.Refresh()).To(gomega.Succeed())
+		available = false
+		gomega.Expect(remote.Refresh()).NotTo(gomega.Succeed())
+		key, err := remote.VerificationKey(keys[0].ID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(key.Public).To(gomega.Equal(keys[0].Public))
+	})
+
+	ginkgo.It("should skip the unsupported keys of a JWK set", func() {
+		keys := getTestKeys()
+		set, err := FromKeys(keys[1:2])
+		gomega.Expect(err).To(gomega.Succeed())
+		set.Keys = append([]JSONWebKey{
+			{Kty: "oct", Kid: "symmetric", Use: KeyUseSignature},
+			{Kty: "EC", Kid: "unknown-curve", Crv: "P-192", Use: KeyUseSignature},
+		}, set.Keys...)
+
+		recovered, err := set.ToKeys()
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(recovered).To(gomega.HaveLen(1))
+		gomega.Expect(recovered[0].ID).To(gomega.Equal(keys[1].ID))
+	})
+
+	ginkgo.It("should refresh the remote JWK set once the refresh interval of the clock elapses", func() {
+		keyring, err := njwt.NewMemoryKeyring(getTestKeys()[0])
+		gomega.Expect(err).To(gomega.Succeed())
+		var requests int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			Handler(keyring).ServeHTTP(w, r)
+		}))
+		defer server.Close()
+
+		clock := njwt.NewFakeClock(time.Now())
+		remote := NewRemoteKeyringWithClock(server.URL, time.Hour, clock)
+		_, err = remote.VerificationKeys()
+		gomega.Expect(err).To(gomega.Succeed())
+		clock.Advance(59 * time.Minute)
+		_, err = remote.VerificationKeys()
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(atomic.LoadInt32(&requests)).To(gomega.Equal(int32(1)))
+		clock.Advance(time.Minute)
+		_, err = remote.VerificationKeys()
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(atomic.LoadInt32(&requests)).To(gomega.Equal(int32(2)))
+	})
+
+	ginkgo.It("should return the cached keys while the remote JWK set is being retrieved", func() {
+		keys := getTestKeys()
+		keyring, err := njwt.NewMemoryKeyring(keys[0])
+		gomega.Expect(err).To(gomega.Succeed())
+		var requests int32
+		release := make(chan struct{})
+		var blocked atomic.Bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if blocked.Load() {
+				<-release
+			}
+			Handler(keyring).ServeHTTP(w, r)
+		}))
+		defer server.Close()
+
+		remote := NewRemoteKeyring(server.URL, time.Hour)
+		gomega.Expect(remote.Refresh()).To(gomega.Succeed())
+		gomega.Expect(atomic.LoadInt32(&requests)).To(gomega.Equal(int32(1)))
+
+		// Force a refresh on the next lookup with a slow endpoint.
+		blocked.Store(true)
+		remote.refreshInterval = 0
+		key, err := remote.VerificationKey(keys[0].ID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(key.Public).To(gomega.Equal(keys[0].Public))
+
+		gomega.Eventually(func() int32 { return atomic.LoadInt32(&requests) }).Should(gomega.Equal(int32(2)))
+
+		// Refreshes requested meanwhile share the retrieval in progress.
+		remote.Lock()
+		call := remote.inflight
+		remote.Unlock()
+		gomega.Expect(call).NotTo(gomega.BeNil())
+		for i := 0; i < 3; i++ {
+			remote.Lock()
+			gomega.Expect(remote.startRefresh()).To(gomega.BeIdenticalTo(call))
+			remote.Unlock()
+		}
+		key, err = remote.VerificationKey(keys[0].ID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(key).NotTo(gomega.BeNil())
+		close(release)
+		<-call.done
+		gomega.Expect(call.err).To(gomega.Succeed())
+		gomega.Expect(atomic.LoadInt32(&requests)).To(gomega.Equal(int32(2)))
+	})
+})
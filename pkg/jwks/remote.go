@@ -0,0 +1,215 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultRefreshInterval with the time after which the cached keys are retrieved again.
+	DefaultRefreshInterval = time.Hour
+	// DefaultMinRefreshInterval with the minimum time between two consecutive retrievals triggered by
+	// unknown key identifiers.
+	DefaultMinRefreshInterval = 30 * time.Second
+	// ClientTimeout with the maximum time to retrieve the remote JWK set.
+	ClientTimeout = 30 * time.Second
+)
+
+// RemoteKeyring is a verification only Keyring that retrieves the keys from a remote JWK set. Keys are
+// cached and refreshed periodically, or when a token references an unknown key identifier, so that keys
+// added during a rotation are discovered without waiting for the next refresh.
+type RemoteKeyring struct {
+	sync.RWMutex
+	url                string
+	client             *http.Client
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	keys               map[string]*njwt.Key
+	lastRefresh        time.Time
+	// clock with the current time used to compute the time elapsed since the last refresh.
+	clock njwt.Clock
+	// inflight with the retrieval in progress, shared by the concurrent requests.
+	inflight *keysFetch
+}
+
+// keysFetch is an in-flight retrieval of the remote JWK set.
+type keysFetch struct {
+	// done is closed once the retrieval finishes.
+	done chan struct{}
+	err  error
+}
+
+// NewRemoteKeyring creates a keyring that retrieves the keys from the JWK set published in the given URL.
+// Example:
+//
+//	keyring := jwks.NewRemoteKeyring("https://zone.example.com/.well-known/jwks.json", jwks.DefaultRefreshInterval)
+//	s = grpc.NewServer(interceptors.WithZoneAwareJWTInterceptor(cfg, secretProvider, interceptors.WithKeyring(keyring)))
+func NewRemoteKeyring(url string, refreshInterval time.Duration) *RemoteKeyring {
+	return NewRemoteKeyringWithClock(url, refreshInterval, njwt.SystemClock)
+}
+
+// NewRemoteKeyringWithClock creates a keyring like NewRemoteKeyring that computes the time elapsed since the last
+// refresh with the given clock, so that the tests do not have to wait for the cached keys to expire.
+func NewRemoteKeyringWithClock(url string, refreshInterval time.Duration, clock njwt.Clock) *RemoteKeyring {
+	if clock == nil {
+		clock = njwt.SystemClock
+	}
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+	minRefreshInterval := DefaultMinRefreshInterval
+	if refreshInterval < minRefreshInterval {
+		minRefreshInterval = refreshInterval
+	}
+	return &RemoteKeyring{
+		url:                url,
+		client:             &http.Client{Timeout: ClientTimeout},
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+		keys:               make(map[string]*njwt.Key),
+		clock:              clock,
+	}
+}
+
+// SigningKey is not supported as a remote JWK set only contains public keys.
+func (rk *RemoteKeyring) SigningKey() (*njwt.Key, error) {
+	return nil, nerrors.NewUnimplementedError("remote keyrings cannot sign tokens")
+}
+
+// VerificationKey returns the key associated with a given key identifier. Cached keys are returned while the
+// JWK set is refreshed in background, so that a slow endpoint does not stall the verification of the tokens.
+func (rk *RemoteKeyring) VerificationKey(kid string) (*njwt.Key, error) {
+	if key, exists := rk.cachedKey(kid); exists {
+		rk.refreshIfNeeded(rk.refreshInterval, false)
+		return key, nil
+	}
+	// The key may have been published after the last refresh.
+	rk.refreshIfNeeded(rk.minRefreshInterval, true)
+	key, exists := rk.cachedKey(kid)
+	if !exists {
+		return nil, nerrors.NewNotFoundError("key [%s] not found", kid)
+	}
+	return key, nil
+}
+
+// cachedKey returns the cached key with the given identifier.
+func (rk *RemoteKeyring) cachedKey(kid string) (*njwt.Key, bool) {
+	rk.RLock()
+	defer rk.RUnlock()
+	key, exists := rk.keys[kid]
+	return key, exists
+}
+
+// VerificationKeys returns all the keys that are accepted to verify tokens.
+func (rk *RemoteKeyring) VerificationKeys() ([]*njwt.Key, error) {
+	rk.refreshIfNeeded(rk.refreshInterval, true)
+	rk.RLock()
+	defer rk.RUnlock()
+	keys := make([]*njwt.Key, 0, len(rk.keys))
+	for _, key := range rk.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Refresh retrieves the remote JWK set replacing the cached keys.
+func (rk *RemoteKeyring) Refresh() error {
+	rk.Lock()
+	call := rk.startRefresh()
+	rk.Unlock()
+	<-call.done
+	return call.err
+}
+
+// refreshIfNeeded starts the retrieval of the remote JWK set if the last refresh is older than the given
+// interval. If wait is set, it also waits for the retrieval in progress.
+func (rk *RemoteKeyring) refreshIfNeeded(interval time.Duration, wait bool) {
+	rk.Lock()
+	call := rk.inflight
+	if call == nil && rk.clock.Now().Sub(rk.lastRefresh) >= interval {
+		call = rk.startRefresh()
+	}
+	rk.Unlock()
+	if call != nil && wait {
+		<-call.done
+	}
+}
+
+// startRefresh starts the retrieval of the remote JWK set, unless there is one in progress. The lock is not
+// held during the retrieval, so that the cached keys remain available. This method expects the lock to be held.
+func (rk *RemoteKeyring) startRefresh() *keysFetch {
+	if rk.inflight != nil {
+		return rk.inflight
+	}
+	// Failed attempts also count to avoid flooding the remote endpoint.
+	rk.lastRefresh = rk.clock.Now()
+	call := &keysFetch{done: make(chan struct{})}
+	rk.inflight = call
+	go func() {
+		keys, err := rk.retrieve()
+		if err != nil {
+			log.Error().Err(err).Str("url", rk.url).Msg("unable to refresh JWK set, using cached keys")
+		}
+		rk.Lock()
+		if err == nil {
+			rk.keys = make(map[string]*njwt.Key, len(keys))
+			for _, key := range keys {
+				rk.keys[key.ID] = key
+			}
+		}
+		rk.inflight = nil
+		rk.Unlock()
+		call.err = err
+		close(call.done)
+	}()
+	return call
+}
+
+// retrieve the keys of the remote JWK set.
+func (rk *RemoteKeyring) retrieve() ([]*njwt.Key, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ClientTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rk.url, nil)
+	if err != nil {
+		return nil, nerrors.NewInvalidArgumentErrorFrom(err, "invalid JWK set URL")
+	}
+	request.Header.Set("Accept", ContentType+", application/json")
+
+	log.Debug().Str("url", rk.url).Msg("loading JWK set")
+	response, err := rk.client.Do(request)
+	if err != nil {
+		return nil, nerrors.NewUnavailableErrorFrom(err, "unable to retrieve JWK set")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, nerrors.NewUnavailableError("unable to retrieve JWK set, unexpected status %d", response.StatusCode)
+	}
+	set := &JSONWebKeySet{}
+	if err := json.NewDecoder(response.Body).Decode(set); err != nil {
+		return nil, nerrors.NewInternalErrorFrom(err, "invalid JWK set")
+	}
+	return set.ToKeys()
+}
@@ -36,7 +36,16 @@ type Claim struct {
 
 // NewClaim create a new Claim instance.
 func NewClaim(issuer string, expiration time.Duration, pc interface{}) *Claim {
-	ct := time.Now()
+	return NewClaimWithClock(SystemClock, issuer, expiration, pc)
+}
+
+// NewClaimWithClock creates a new Claim instance issued at the current time of the given clock.
+// Example:
+//
+//	clock := NewFakeClock(time.Now().Add(-2 * time.Hour))
+//	expiredClaim := NewClaimWithClock(clock, "tt", time.Hour, pc)
+func NewClaimWithClock(clock Clock, issuer string, expiration time.Duration, pc interface{}) *Claim {
+	ct := getClock(clock).Now()
 	standardClaim := jwt.StandardClaims{
 		Id:        generateUUID(),
 		Issuer:    issuer,
@@ -47,6 +56,16 @@ func NewClaim(issuer string, expiration time.Duration, pc interface{}) *Claim {
 	return &Claim{StandardClaims: standardClaim, PersonalClaim: pc}
 }
 
+// WithAudience sets the audience of the claim, so that the token is only accepted by the services that
+// expect that audience.
+// Example:
+//
+//	claim := NewClaim("authx", time.Hour, pc).WithAudience("playground")
+func (c *Claim) WithAudience(audience string) *Claim {
+	c.Audience = audience
+	return c
+}
+
 // AuthxClaim is the information stored by Authx in the claim.
 type AuthxClaim struct {
 	// UserID internal napptive user identifier.
@@ -165,6 +184,25 @@ func (ac *AuthxClaim) IsAuthorized(accountName string, adminRoleRequired bool) b
 	return authorized
 }
 
+// HasRole checks if the user (claim) has one of the given roles in an account. If no roles are provided,
+// belonging to the account is enough.
+func (ac *AuthxClaim) HasRole(accountName string, roles ...string) bool {
+	for _, account := range ac.Accounts {
+		if account.Name == accountName {
+			if len(roles) == 0 {
+				return true
+			}
+			for _, role := range roles {
+				if account.Role == role {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
 // GetCurrentAccountName returns the EnvironmentAccountID name
 // The AccountName is a deprecated field, the name can be retrieved from the accounts list
 func (ac *AuthxClaim) GetCurrentAccountName() (*string, error) {
@@ -178,9 +216,10 @@ func (ac *AuthxClaim) GetCurrentAccountName() (*string, error) {
 	return nil, nerrors.NewInternalError("error getting account name from claim. Account %s not found in the user accounts", ac.EnvironmentAccountID)
 }
 
-// GetAuthxClaim returns the AuthxClaim section of the claim.
+// GetAuthxClaim returns the AuthxClaim section of the claim, or nil if the personal claim is not an AuthxClaim.
 func (c *Claim) GetAuthxClaim() *AuthxClaim {
-	return c.PersonalClaim.(*AuthxClaim)
+	pc, _ := c.PersonalClaim.(*AuthxClaim)
+	return pc
 }
 
 // ExtendedAuthxClaim combining the standard and authx claims.
@@ -200,8 +239,10 @@ func (eac *ExtendedAuthxClaim) ToClaim() *Claim {
 // RefreshClaim is the information stored to create the refresh token.
 type RefreshClaim struct {
 	UserID string
-	// The tokenID is included to avoid in the future token control.
+	// TokenID with the identifier (jti) of the access token issued with the refresh token.
 	TokenID string
+	// FamilyID with the identifier of the chain of refresh tokens obtained by rotation from the same login.
+	FamilyID string `json:",omitempty"`
 }
 
 // NewRefreshClaim create a new instance of RefreshClaim
@@ -0,0 +1,159 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time used to issue and validate the tokens, so that it can be replaced in the
+// tests or to simulate clock skew.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// Timer is a function scheduled by a clock.
+type Timer interface {
+	// Stop prevents the function from being called. It returns false if the function has already been called
+	// or the timer was already stopped.
+	Stop() bool
+}
+
+// TimerClock is a Clock that also schedules functions, so that the timers follow the time of the clock.
+type TimerClock interface {
+	Clock
+	// AfterFunc calls a function in its own goroutine once the given duration has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// AfterFunc calls a function in its own goroutine once the given duration has elapsed in the clock. Clocks
+// that do not implement TimerClock use the timers of the system.
+func AfterFunc(clock Clock, d time.Duration, f func()) Timer {
+	if timerClock, ok := getClock(clock).(TimerClock); ok {
+		return timerClock.AfterFunc(d, f)
+	}
+	return time.AfterFunc(d, f)
+}
+
+// systemClock is the Clock that returns the time of the system.
+type systemClock struct{}
+
+// Now returns the current time of the system.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// AfterFunc schedules a function with the timers of the system.
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// SystemClock is the Clock used by default.
+var SystemClock Clock = systemClock{}
+
+// getClock returns the given clock, or the system clock if it is nil.
+func getClock(clock Clock) Clock {
+	if clock == nil {
+		return SystemClock
+	}
+	return clock
+}
+
+// FakeClock is a clock whose time only changes when the test moves it. The functions scheduled with AfterFunc
+// are called when the clock is moved past their time.
+type FakeClock struct {
+	sync.RWMutex
+	now time.Time
+	// timers with the scheduled functions that have not been called yet.
+	timers []*fakeTimer
+}
+
+// fakeTimer is a function scheduled in a FakeClock.
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	f     func()
+}
+
+// NewFakeClock creates a clock stopped at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the current time of the clock.
+func (fc *FakeClock) Now() time.Time {
+	fc.RLock()
+	defer fc.RUnlock()
+	return fc.now
+}
+
+// Set moves the clock to the given time.
+func (fc *FakeClock) Set(now time.Time) {
+	fc.Lock()
+	fc.now = now
+	fc.fire()
+}
+
+// Advance moves the clock forward, or backward if the duration is negative.
+func (fc *FakeClock) Advance(d time.Duration) {
+	fc.Lock()
+	fc.now = fc.now.Add(d)
+	fc.fire()
+}
+
+// AfterFunc calls a function in its own goroutine once the clock is moved past the given duration.
+func (fc *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	fc.Lock()
+	timer := &fakeTimer{clock: fc, when: fc.now.Add(d), f: f}
+	fc.timers = append(fc.timers, timer)
+	fc.fire()
+	return timer
+}
+
+// fire calls the functions whose time has been reached, removing them from the scheduled ones. The caller must
+// hold the lock, that is released before calling the functions.
+func (fc *FakeClock) fire() {
+	pending := make([]*fakeTimer, 0, len(fc.timers))
+	due := make([]*fakeTimer, 0)
+	for _, timer := range fc.timers {
+		if timer.when.After(fc.now) {
+			pending = append(pending, timer)
+		} else {
+			due = append(due, timer)
+		}
+	}
+	fc.timers = pending
+	fc.Unlock()
+	for _, timer := range due {
+		go timer.f()
+	}
+}
+
+// Stop removes the function from the scheduled ones of the clock.
+func (ft *fakeTimer) Stop() bool {
+	ft.clock.Lock()
+	defer ft.clock.Unlock()
+	for index, timer := range ft.clock.timers {
+		if timer == ft {
+			ft.clock.timers = append(ft.clock.timers[:index], ft.clock.timers[index+1:]...)
+			return true
+		}
+	}
+	return false
+}
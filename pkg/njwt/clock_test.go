@@ -0,0 +1,133 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("njwt clock tests", func() {
+
+	var clock *FakeClock
+
+	ginkgo.BeforeEach(func() {
+		clock = NewFakeClock(time.Now())
+	})
+
+	ginkgo.It("should issue and validate the tokens with the time of the clock", func() {
+		tokenMgr := NewWithClock(clock)
+		claim := NewClaimWithClock(clock, "tt", time.Hour, GenerateTestAuthxClaim())
+		gomega.Expect(claim.IssuedAt).Should(gomega.Equal(clock.Now().Unix()))
+		token, err := tokenMgr.Generate(claim, "secret")
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		clock.Advance(59 * time.Minute)
+		_, err = tokenMgr.Recover(*token, "secret", &AuthxClaim{})
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		clock.Advance(2 * time.Minute)
+		_, err = tokenMgr.Recover(*token, "secret", &AuthxClaim{})
+		expectValidationError(err, jwt.ValidationErrorExpired)
+		_, err = tokenMgr.Recover(*token, "secret", &AuthxClaim{}, &ValidationOptions{Leeway: 5 * time.Minute})
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+
+	ginkgo.It("should simulate the clock skew of the issuer", func() {
+		issuerClock := NewFakeClock(clock.Now().Add(time.Minute))
+		token, err := New().Generate(NewClaimWithClock(issuerClock, "tt", time.Hour, GenerateTestAuthxClaim()), "secret")
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		_, err = New().Recover(*token, "secret", &AuthxClaim{}, &ValidationOptions{Clock: clock})
+		expectValidationError(err, jwt.ValidationErrorNotValidYet)
+		_, err = New().Recover(*token, "secret", &AuthxClaim{}, &ValidationOptions{Clock: clock, Leeway: time.Minute})
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+
+	ginkgo.It("should check the expiration of the tokens with the clock", func() {
+		token, err := New().Generate(NewClaimWithClock(clock, "tt", time.Hour, GenerateTestAuthxClaim()), "secret")
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		expired, err := IsTokenExpiredWithClock(*token, clock, time.Minute)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(*expired).Should(gomega.BeFalse())
+
+		clock.Advance(59 * time.Minute)
+		expired, err = IsTokenExpiredWithClock(*token, clock, time.Minute)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(*expired).Should(gomega.BeTrue())
+	})
+
+	ginkgo.It("should expire the sessions with the time of the clock", func() {
+		tokenMgr, err := NewWithKey(NewHMACKey("key", "secret"))
+		gomega.Expect(err).Should(gomega.Succeed())
+		sessionMgr, err := NewSessionManager(tokenMgr, NewMemoryRevocationStore(), SessionConfig{
+			AccessExpiration: time.Minute, RefreshExpiration: time.Hour, Clock: clock})
+		gomega.Expect(err).Should(gomega.Succeed())
+		pair, err := sessionMgr.Issue(GenerateTestAuthxClaim())
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		clock.Advance(2 * time.Hour)
+		_, err = sessionMgr.Refresh(pair.RefreshToken, nil)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+	})
+
+	ginkgo.It("should validate the tokens of a keyed manager with the time of the clock", func() {
+		keyring, err := NewMemoryKeyring(NewHMACKey("key", "secret"))
+		gomega.Expect(err).Should(gomega.Succeed())
+		tokenMgr, err := NewWithKeyringAndClock(keyring, clock)
+		gomega.Expect(err).Should(gomega.Succeed())
+		token, err := tokenMgr.Generate(NewClaimWithClock(clock, "tt", time.Hour, GenerateTestAuthxClaim()))
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		_, err = tokenMgr.Recover(*token, &AuthxClaim{})
+		gomega.Expect(err).Should(gomega.Succeed())
+		clock.Advance(2 * time.Hour)
+		_, err = tokenMgr.Recover(*token, &AuthxClaim{})
+		expectValidationError(err, jwt.ValidationErrorExpired)
+	})
+
+	ginkgo.It("should prune the revocations with the time of the clock", func() {
+		store := NewMemoryRevocationStoreWithClock(clock)
+		gomega.Expect(store.Revoke("expiring", clock.Now().Add(time.Minute))).To(gomega.Succeed())
+		clock.Advance(2 * time.Minute)
+		gomega.Expect(store.Revoke("other", clock.Now().Add(time.Hour))).To(gomega.Succeed())
+		revoked, err := store.IsRevoked("expiring")
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(revoked).Should(gomega.BeFalse())
+	})
+
+	ginkgo.It("should call the scheduled functions when the clock is moved", func() {
+		fired := make(chan string, 2)
+		AfterFunc(clock, time.Minute, func() { fired <- "first" })
+		stopped := AfterFunc(clock, time.Minute, func() { fired <- "stopped" })
+		AfterFunc(clock, time.Hour, func() { fired <- "last" })
+		gomega.Expect(stopped.Stop()).Should(gomega.BeTrue())
+
+		clock.Advance(30 * time.Second)
+		gomega.Consistently(fired, 50*time.Millisecond).ShouldNot(gomega.Receive())
+		clock.Advance(30 * time.Second)
+		gomega.Eventually(fired).Should(gomega.Receive(gomega.Equal("first")))
+		gomega.Consistently(fired, 50*time.Millisecond).ShouldNot(gomega.Receive())
+		clock.Set(clock.Now().Add(time.Hour))
+		gomega.Eventually(fired).Should(gomega.Receive(gomega.Equal("last")))
+		gomega.Expect(stopped.Stop()).Should(gomega.BeFalse())
+	})
+})
@@ -17,6 +17,7 @@
 package njwt
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -34,6 +35,13 @@ const (
 // WARNING: This method does not check the signature of the token, so use it only if you
 // understand the security downside.
 func IsTokenExpired(rawToken string, margin ...time.Duration) (*bool, error) {
+	return IsTokenExpiredWithClock(rawToken, SystemClock, margin...)
+}
+
+// IsTokenExpiredWithClock checks the expiration date of a given raw token at the current time of the clock,
+// applying a margin.
+// WARNING: This method does not check the signature of the token.
+func IsTokenExpiredWithClock(rawToken string, clock Clock, margin ...time.Duration) (*bool, error) {
 	parser := &jwt.Parser{}
 	token, _, err := parser.ParseUnverified(rawToken, jwt.MapClaims{})
 	if err != nil {
@@ -54,7 +62,7 @@ func IsTokenExpired(rawToken string, margin ...time.Duration) (*bool, error) {
 
 	log.Debug().Str("claimExpireTime", claimExpireTime.String()).Str("effectiveExpirationTime", effectiveExpirationTime.String()).Msg("claims")
 
-	result := time.Now().Unix() >= effectiveExpirationTime.Unix()
+	result := getClock(clock).Now().Unix() >= effectiveExpirationTime.Unix()
 	return &result, nil
 }
 
@@ -64,6 +72,19 @@ func getExpirationTime(claims jwt.MapClaims) (*time.Time, error) {
 	if !exists {
 		return nil, nerrors.NewInvalidArgumentError("claims do not contain expiration (exp) field")
 	}
-	claimExpireTime := time.Unix(int64(rawExp.(float64)), 0)
+	var exp int64
+	switch value := rawExp.(type) {
+	case float64:
+		exp = int64(value)
+	case json.Number:
+		parsed, err := value.Int64()
+		if err != nil {
+			return nil, nerrors.NewInvalidArgumentErrorFrom(err, "expiration (exp) field must be a number")
+		}
+		exp = parsed
+	default:
+		return nil, nerrors.NewInvalidArgumentError("expiration (exp) field must be a number")
+	}
+	claimExpireTime := time.Unix(exp, 0)
 	return &claimExpireTime, nil
 }
@@ -0,0 +1,155 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+)
+
+// KeyedTokenManager is an interface that generate new tokens using the key material bound to the
+// manager instead of a shared secret. Services that only hold the public part of the key are able
+// to verify tokens, but not to create them.
+type KeyedTokenManager interface {
+	// Generate a new token with a claim.
+	Generate(claim *Claim) (*string, error)
+	// Recover the claim from a token, if you want to recover the personal claim you must include the appropriate object.
+	// Optionally, a set of validation options can be passed to check the standard claims.
+	// Example:
+	//   recoveredClaim, err := tokenMgr.Recover(*token, &AuthxClaim{})
+	Recover(tk string, pc interface{}, opts ...*ValidationOptions) (*Claim, error)
+	// RecoverUnverified parses the token returning the parsed claim.
+	// NOTICE: This method does not verify the authenticity of the token.
+	RecoverUnverified(tk string, pc interface{}) (*Claim, error)
+}
+
+// NewWithKey creates a token manager that signs and verifies tokens with the given key. The list of
+// allowed algorithms restricts the tokens accepted by Recover, and it defaults to the algorithm of the key.
+// Example:
+//
+//	key, err := NewSigningKey("2023-10", jwt.SigningMethodES256, privateKey)
+//	tokenMgr, err := NewWithKey(key)
+func NewWithKey(key *Key, allowedAlgorithms ...string) (KeyedTokenManager, error) {
+	if key == nil {
+		return nil, nerrors.NewInvalidArgumentError("key must be provided")
+	}
+	if len(allowedAlgorithms) == 0 {
+		allowedAlgorithms = []string{key.Method.Alg()}
+	}
+	var keyring *MemoryKeyring
+	var err error
+	if key.CanSign() {
+		keyring, err = NewMemoryKeyring(key)
+	} else {
+		keyring, err = NewMemoryKeyring(nil, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewWithKeyring(keyring, allowedAlgorithms...)
+}
+
+// NewWithKeyring creates a token manager that signs tokens with the active key of the keyring, and verifies
+// them with the key referenced by their kid header. If a list of allowed algorithms is provided, tokens
+// using other algorithms are rejected. In any case, the algorithm of a token must match the one of its key.
+func NewWithKeyring(keyring Keyring, allowedAlgorithms ...string) (KeyedTokenManager, error) {
+	return NewWithKeyringAndClock(keyring, SystemClock, allowedAlgorithms...)
+}
+
+// NewWithKeyringAndClock creates a token manager like NewWithKeyring that validates the tokens with the time of
+// the given clock, unless the validation options set a different one.
+// Example:
+//
+//	clock := NewFakeClock(time.Now())
+//	tokenMgr, err := NewWithKeyringAndClock(keyring, clock)
+//	token, err := tokenMgr.Generate(NewClaimWithClock(clock, "tt", time.Hour, pc))
+//	clock.Advance(2 * time.Hour)
+//	_, err = tokenMgr.Recover(*token, &AuthxClaim{}) // expired
+func NewWithKeyringAndClock(keyring Keyring, clock Clock, allowedAlgorithms ...string) (KeyedTokenManager, error) {
+	if keyring == nil {
+		return nil, nerrors.NewInvalidArgumentError("keyring must be provided")
+	}
+	if len(allowedAlgorithms) > 0 {
+		keys, err := keyring.VerificationKeys()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if !contains(allowedAlgorithms, key.Method.Alg()) {
+				return nil, nerrors.NewInvalidArgumentError("algorithm %s of key [%s] is not in the list of allowed algorithms", key.Method.Alg(), key.ID)
+			}
+		}
+	}
+	return &keyedManager{
+		keyring:           keyring,
+		allowedAlgorithms: allowedAlgorithms,
+		clock:             getClock(clock),
+	}, nil
+}
+
+type keyedManager struct {
+	keyring Keyring
+	// allowedAlgorithms with the list of algorithms accepted when recovering a token.
+	allowedAlgorithms []string
+	// clock with the current time used to validate the tokens.
+	clock Clock
+}
+
+// Generate a new token with a claim. The identifier of the signing key is included in the kid header.
+func (km *keyedManager) Generate(claim *Claim) (*string, error) {
+	key, err := km.keyring.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+	if !key.CanSign() {
+		return nil, nerrors.NewFailedPreconditionError("the key of the token manager cannot be used to sign tokens")
+	}
+	tk := jwt.NewWithClaims(key.Method, claim)
+	if key.ID != "" {
+		tk.Header[KeyIDHeader] = key.ID
+	}
+	tokenString, err := tk.SignedString(key.Private)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenString, nil
+}
+
+// Recover the claim from a token, if you want to recover the personal claim you must include the appropriate object.
+func (km *keyedManager) Recover(tk string, pc interface{}, opts ...*ValidationOptions) (*Claim, error) {
+	parser := &jwt.Parser{}
+	if len(km.allowedAlgorithms) > 0 {
+		parser.ValidMethods = km.allowedAlgorithms
+	}
+	return parse(parser, tk, pc, KeyringKeyFunc(km.keyring), withClock(km.clock, opts...))
+}
+
+// RecoverUnverified parses the token returning the parsed claim.
+// NOTICE: This method does not verify the authenticity of the token.
+func (km *keyedManager) RecoverUnverified(tk string, pc interface{}) (*Claim, error) {
+	return recoverUnverified(tk, pc)
+}
+
+// contains checks if a value is present in a list.
+func contains(list []string, value string) bool {
+	for _, current := range list {
+		if current == value {
+			return true
+		}
+	}
+	return false
+}
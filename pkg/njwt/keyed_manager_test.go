@@ -0,0 +1,133 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// generateTestSigner returns a new private key compatible with the given signing method.
+func generateTestSigner(method jwt.SigningMethod) crypto.Signer {
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		gomega.Expect(err).To(gomega.Succeed())
+		return privateKey
+	case *jwt.SigningMethodECDSA:
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		gomega.Expect(err).To(gomega.Succeed())
+		return privateKey
+	default:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		gomega.Expect(err).To(gomega.Succeed())
+		return privateKey
+	}
+}
+
+var _ = ginkgo.Describe("njwt Keyed Token Manager tests", func() {
+
+	methods := []jwt.SigningMethod{jwt.SigningMethodRS256, jwt.SigningMethodES256, jwt.SigningMethodEdDSA}
+
+	for _, m := range methods {
+		method := m
+		ginkgo.It("Check a normal lifecycle with "+method.Alg(), func() {
+			privateKey := generateTestSigner(method)
+			signingKey, err := NewSigningKey("", method, privateKey)
+			gomega.Expect(err).To(gomega.Succeed())
+			signer, err := NewWithKey(signingKey)
+			gomega.Expect(err).To(gomega.Succeed())
+
+			pc := GenerateTestAuthxClaim()
+			claim := NewClaim("tt", time.Hour, pc)
+			token, err := signer.Generate(claim)
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(token).NotTo(gomega.BeNil())
+
+			verificationKey, err := NewVerificationKey("", method, privateKey.Public())
+			gomega.Expect(err).To(gomega.Succeed())
+			verifier, err := NewWithKey(verificationKey)
+			gomega.Expect(err).To(gomega.Succeed())
+
+			recClaim, err := verifier.Recover(*token, &AuthxClaim{})
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(recClaim.GetAuthxClaim()).To(gomega.Equal(pc))
+
+			_, err = verifier.Generate(claim)
+			gomega.Expect(err).NotTo(gomega.Succeed())
+		})
+	}
+
+	ginkgo.It("should reject keys that do not match the algorithm", func() {
+		privateKey := generateTestSigner(jwt.SigningMethodRS256)
+		_, err := NewSigningKey("", jwt.SigningMethodES256, privateKey)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = NewVerificationKey("", jwt.SigningMethodEdDSA, privateKey.Public())
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should reject tokens signed with a different key", func() {
+		signingKey, err := NewSigningKey("", jwt.SigningMethodES256, generateTestSigner(jwt.SigningMethodES256))
+		gomega.Expect(err).To(gomega.Succeed())
+		signer, err := NewWithKey(signingKey)
+		gomega.Expect(err).To(gomega.Succeed())
+		token, err := signer.Generate(NewClaim("tt", time.Hour, nil))
+		gomega.Expect(err).To(gomega.Succeed())
+
+		otherKey, err := NewSigningKey("", jwt.SigningMethodES256, generateTestSigner(jwt.SigningMethodES256))
+		gomega.Expect(err).To(gomega.Succeed())
+		verifier, err := NewWithKey(otherKey)
+		gomega.Expect(err).To(gomega.Succeed())
+		recClaim, err := verifier.Recover(*token, nil)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(recClaim).To(gomega.BeNil())
+	})
+
+	ginkgo.It("should reject HMAC tokens signed with the public key", func() {
+		privateKey := generateTestSigner(jwt.SigningMethodRS256).(*rsa.PrivateKey)
+		verificationKey, err := NewVerificationKey("", jwt.SigningMethodRS256, privateKey.Public())
+		gomega.Expect(err).To(gomega.Succeed())
+		verifier, err := NewWithKey(verificationKey)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		rawPublicKey, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+		gomega.Expect(err).To(gomega.Succeed())
+		token, err := New().Generate(NewClaim("tt", time.Hour, nil), string(rawPublicKey))
+		gomega.Expect(err).To(gomega.Succeed())
+
+		recClaim, err := verifier.Recover(*token, nil)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(recClaim).To(gomega.BeNil())
+	})
+
+	ginkgo.It("should reject an allowlist that does not include the algorithm of the key", func() {
+		signingKey, err := NewSigningKey("", jwt.SigningMethodEdDSA, generateTestSigner(jwt.SigningMethodEdDSA))
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = NewWithKey(signingKey, jwt.SigningMethodRS256.Alg())
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
@@ -0,0 +1,172 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/rs/zerolog/log"
+)
+
+// KeyIDHeader with the name of the token header that contains the key identifier.
+const KeyIDHeader = "kid"
+
+// Keyring defines the methods required to obtain the keys used to sign and verify tokens. A keyring
+// enables key rotation by keeping a set of verification only keys alongside the active signing key.
+type Keyring interface {
+	// SigningKey returns the active key used to sign new tokens.
+	SigningKey() (*Key, error)
+	// VerificationKey returns the key associated with a given key identifier. Tokens without a kid
+	// header are verified with the key whose identifier is empty.
+	VerificationKey(kid string) (*Key, error)
+	// VerificationKeys returns all the keys that are accepted to verify tokens.
+	VerificationKeys() ([]*Key, error)
+}
+
+// MemoryKeyring is a Keyring that stores the keys in memory. It contains an active key used to sign
+// new tokens, and a set of retired keys that are only used to verify tokens issued before a rotation.
+type MemoryKeyring struct {
+	sync.RWMutex
+	active  *Key
+	retired []*Key
+}
+
+// NewMemoryKeyring creates a keyring with an active signing key and a set of retired keys. The active
+// key may be nil for services that are only expected to verify tokens.
+func NewMemoryKeyring(active *Key, retired ...*Key) (*MemoryKeyring, error) {
+	keyring := &MemoryKeyring{}
+	if err := keyring.checkKeys(active, retired...); err != nil {
+		return nil, err
+	}
+	keyring.active = active
+	keyring.retired = retired
+	return keyring, nil
+}
+
+// checkKeys validates a set of keys making sure that there are no duplicated identifiers.
+func (mk *MemoryKeyring) checkKeys(active *Key, retired ...*Key) error {
+	ids := make(map[string]bool, 0)
+	all := retired
+	if active != nil {
+		if !active.CanSign() {
+			return nerrors.NewInvalidArgumentError("active key must be able to sign tokens")
+		}
+		all = append([]*Key{active}, retired...)
+	}
+	for _, key := range all {
+		if key == nil {
+			return nerrors.NewInvalidArgumentError("keys must not be nil")
+		}
+		if err := key.IsValid(); err != nil {
+			return err
+		}
+		if ids[key.ID] {
+			return nerrors.NewInvalidArgumentError("duplicated key identifier [%s]", key.ID)
+		}
+		ids[key.ID] = true
+	}
+	return nil
+}
+
+// Rotate sets a new active key. The previous active key is kept as a retired key so that the tokens signed
+// with it are still valid until it is removed.
+func (mk *MemoryKeyring) Rotate(active *Key) error {
+	mk.Lock()
+	defer mk.Unlock()
+	retired := mk.retired
+	if mk.active != nil {
+		retired = append([]*Key{mk.active}, retired...)
+	}
+	if err := mk.checkKeys(active, retired...); err != nil {
+		return err
+	}
+	mk.active = active
+	mk.retired = retired
+	return nil
+}
+
+// Remove a retired key from the keyring. Tokens signed with the key will no longer be accepted.
+func (mk *MemoryKeyring) Remove(kid string) error {
+	mk.Lock()
+	defer mk.Unlock()
+	for index, key := range mk.retired {
+		if key.ID == kid {
+			mk.retired = append(mk.retired[:index:index], mk.retired[index+1:]...)
+			return nil
+		}
+	}
+	return nerrors.NewNotFoundError("retired key [%s] not found", kid)
+}
+
+// SigningKey returns the active key used to sign new tokens.
+func (mk *MemoryKeyring) SigningKey() (*Key, error) {
+	mk.RLock()
+	defer mk.RUnlock()
+	if mk.active == nil {
+		return nil, nerrors.NewFailedPreconditionError("keyring does not contain a signing key")
+	}
+	return mk.active, nil
+}
+
+// VerificationKey returns the key associated with a given key identifier.
+func (mk *MemoryKeyring) VerificationKey(kid string) (*Key, error) {
+	mk.RLock()
+	defer mk.RUnlock()
+	if mk.active != nil && mk.active.ID == kid {
+		return mk.active, nil
+	}
+	for _, key := range mk.retired {
+		if key.ID == kid {
+			return key, nil
+		}
+	}
+	return nil, nerrors.NewNotFoundError("key [%s] not found", kid)
+}
+
+// VerificationKeys returns all the keys that are accepted to verify tokens.
+func (mk *MemoryKeyring) VerificationKeys() ([]*Key, error) {
+	mk.RLock()
+	defer mk.RUnlock()
+	keys := make([]*Key, 0, len(mk.retired)+1)
+	if mk.active != nil {
+		keys = append(keys, mk.active)
+	}
+	return append(keys, mk.retired...), nil
+}
+
+// KeyringKeyFunc returns a function that selects the key to verify a token attending to its kid
+// header. The algorithm of the token must match the one of the selected key.
+func KeyringKeyFunc(keyring Keyring) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header[KeyIDHeader].(string)
+		key, err := keyring.VerificationKey(kid)
+		if err != nil {
+			log.Error().Err(err).Str("kid", kid).Msg("unable to retrieve the key associated with the token")
+			return nil, err
+		}
+		// The algorithm of the token must match the one of the key to avoid algorithm confusion attacks, for
+		// example, verifying an HMAC token using the public key as the secret.
+		if token.Method.Alg() != key.Method.Alg() {
+			log.Error().Str("token", token.Raw).Msg("token not generated by napptive, invalid algorithm")
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.Public, nil
+	}
+}
@@ -0,0 +1,94 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("njwt Keyring tests", func() {
+
+	ginkgo.It("should stamp the kid header of the signing key", func() {
+		keyring, err := NewMemoryKeyring(NewHMACKey("v1", "secret"))
+		gomega.Expect(err).To(gomega.Succeed())
+		tokenMgr, err := NewWithKeyring(keyring)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		token, err := tokenMgr.Generate(NewClaim("tt", time.Hour, nil))
+		gomega.Expect(err).To(gomega.Succeed())
+		parsed, _, err := new(jwt.Parser).ParseUnverified(*token, &Claim{})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.Header[KeyIDHeader]).To(gomega.Equal("v1"))
+	})
+
+	ginkgo.It("should accept tokens of retired keys until they are removed", func() {
+		keyring, err := NewMemoryKeyring(NewHMACKey("v1", "secret"))
+		gomega.Expect(err).To(gomega.Succeed())
+		tokenMgr, err := NewWithKeyring(keyring)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		pc := GenerateTestAuthxClaim()
+		oldToken, err := tokenMgr.Generate(NewClaim("tt", time.Hour, pc))
+		gomega.Expect(err).To(gomega.Succeed())
+
+		gomega.Expect(keyring.Rotate(NewHMACKey("v2", "newSecret"))).To(gomega.Succeed())
+		newToken, err := tokenMgr.Generate(NewClaim("tt", time.Hour, pc))
+		gomega.Expect(err).To(gomega.Succeed())
+
+		for _, token := range []*string{oldToken, newToken} {
+			recClaim, err := tokenMgr.Recover(*token, &AuthxClaim{})
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(recClaim.GetAuthxClaim()).To(gomega.Equal(pc))
+		}
+
+		gomega.Expect(keyring.Remove("v1")).To(gomega.Succeed())
+		_, err = tokenMgr.Recover(*oldToken, &AuthxClaim{})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = tokenMgr.Recover(*newToken, &AuthxClaim{})
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should verify legacy tokens without kid with the key without identifier", func() {
+		token, err := New().Generate(NewClaim("tt", time.Hour, nil), "secret")
+		gomega.Expect(err).To(gomega.Succeed())
+
+		keyring, err := NewMemoryKeyring(NewHMACKey("v1", "newSecret"), NewHMACKey("", "secret"))
+		gomega.Expect(err).To(gomega.Succeed())
+		tokenMgr, err := NewWithKeyring(keyring)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = tokenMgr.Recover(*token, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should reject duplicated key identifiers", func() {
+		_, err := NewMemoryKeyring(NewHMACKey("v1", "secret"), NewHMACKey("v1", "other"))
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should not be able to sign tokens without an active key", func() {
+		keyring, err := NewMemoryKeyring(nil, NewHMACKey("v1", "secret"))
+		gomega.Expect(err).To(gomega.Succeed())
+		tokenMgr, err := NewWithKeyring(keyring)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = tokenMgr.Generate(NewClaim("tt", time.Hour, nil))
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
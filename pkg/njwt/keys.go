@@ -0,0 +1,141 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+)
+
+// Key contains the material required to sign and verify tokens with a given algorithm.
+type Key struct {
+	// ID with the key identifier that is stamped in the kid header of the tokens signed with this key.
+	ID string
+	// Method with the signing algorithm associated with the key.
+	Method jwt.SigningMethod
+	// Private with the key used to sign new tokens. It is nil for verification only keys.
+	Private interface{}
+	// Public with the key used to verify the signature of the tokens.
+	Public interface{}
+}
+
+// NewSigningKey creates a key that is able to sign and verify tokens. The public part of the key
+// is derived from the private one.
+// Example:
+//
+//	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+//	key, err := NewSigningKey("2023-10", jwt.SigningMethodRS256, privateKey)
+func NewSigningKey(id string, method jwt.SigningMethod, privateKey crypto.Signer) (*Key, error) {
+	if privateKey == nil {
+		return nil, nerrors.NewInvalidArgumentError("private key must be provided")
+	}
+	key := &Key{
+		ID:      id,
+		Method:  method,
+		Private: privateKey,
+		Public:  privateKey.Public(),
+	}
+	if err := key.IsValid(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewVerificationKey creates a key that is only able to verify tokens.
+func NewVerificationKey(id string, method jwt.SigningMethod, publicKey crypto.PublicKey) (*Key, error) {
+	key := &Key{
+		ID:     id,
+		Method: method,
+		Public: publicKey,
+	}
+	if err := key.IsValid(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// NewHMACKey creates a key from a shared secret. The same secret is used to sign and verify tokens, so
+// any holder of the key is able to create new tokens.
+func NewHMACKey(id string, secret string) *Key {
+	return &Key{
+		ID:      id,
+		Method:  jwt.SigningMethodHS256,
+		Private: []byte(secret),
+		Public:  []byte(secret),
+	}
+}
+
+// CanSign checks if the key contains the private material required to sign tokens.
+func (k *Key) CanSign() bool {
+	return k.Private != nil
+}
+
+// IsValid checks that the key material matches the type expected by the signing method.
+func (k *Key) IsValid() error {
+	if k.Method == nil {
+		return nerrors.NewInvalidArgumentError("signing method must be provided")
+	}
+	if k.Public == nil {
+		return nerrors.NewInvalidArgumentError("public key must be provided")
+	}
+	valid := false
+	switch method := k.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		var secret []byte
+		secret, valid = k.Public.([]byte)
+		if valid && len(secret) == 0 {
+			return nerrors.NewInvalidArgumentError("secret must be filled")
+		}
+		if k.Private != nil {
+			_, privateOK := k.Private.([]byte)
+			valid = valid && privateOK
+		}
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, valid = k.Public.(*rsa.PublicKey)
+		if k.Private != nil {
+			_, privateOK := k.Private.(*rsa.PrivateKey)
+			valid = valid && privateOK
+		}
+	case *jwt.SigningMethodECDSA:
+		var publicKey *ecdsa.PublicKey
+		publicKey, valid = k.Public.(*ecdsa.PublicKey)
+		if valid && publicKey.Curve.Params().BitSize != method.CurveBits {
+			return nerrors.NewInvalidArgumentError("curve of the key does not match the %s algorithm", method.Alg())
+		}
+		if k.Private != nil {
+			_, privateOK := k.Private.(*ecdsa.PrivateKey)
+			valid = valid && privateOK
+		}
+	case *jwt.SigningMethodEd25519:
+		_, valid = k.Public.(ed25519.PublicKey)
+		if k.Private != nil {
+			_, privateOK := k.Private.(ed25519.PrivateKey)
+			valid = valid && privateOK
+		}
+	default:
+		return nerrors.NewInvalidArgumentError("unsupported signing method %s", k.Method.Alg())
+	}
+	if !valid {
+		return nerrors.NewInvalidArgumentError("key type does not match the %s algorithm", k.Method.Alg())
+	}
+	return nil
+}
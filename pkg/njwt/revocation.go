@@ -0,0 +1,193 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+)
+
+// RevocationStore defines the methods required to maintain a denylist of tokens attending to their
+// identifier (jti claim).
+type RevocationStore interface {
+	// Revoke a token until its expiration time. Once the token expires, it is rejected anyway and the entry
+	// can be pruned. A zero expiration time keeps the entry forever.
+	Revoke(jti string, expiresAt time.Time) error
+	// IsRevoked checks if a token identifier has been revoked.
+	IsRevoked(jti string) (bool, error)
+	// Prune removes the entries of the tokens that have expired at a given time.
+	Prune(now time.Time) error
+}
+
+// MemoryRevocationStore is a RevocationStore that keeps the revoked identifiers in memory.
+type MemoryRevocationStore struct {
+	sync.RWMutex
+	// revoked maps the revoked identifiers to the expiration time of the token.
+	revoked map[string]time.Time
+	// clock with the current time used to prune the expired entries.
+	clock Clock
+}
+
+// NewMemoryRevocationStore creates an empty in-memory revocation store.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return NewMemoryRevocationStoreWithClock(SystemClock)
+}
+
+// NewMemoryRevocationStoreWithClock creates an empty in-memory revocation store that prunes the expired entries
+// attending to the time of the given clock.
+func NewMemoryRevocationStoreWithClock(clock Clock) *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked: make(map[string]time.Time, 0),
+		clock:   getClock(clock),
+	}
+}
+
+// Revoke a token until its expiration time. Expired entries are pruned on each call.
+func (mrs *MemoryRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nerrors.NewInvalidArgumentError("token identifier cannot be empty")
+	}
+	mrs.Lock()
+	defer mrs.Unlock()
+	mrs.revoked[jti] = expiresAt
+	mrs.prune(mrs.clock.Now())
+	return nil
+}
+
+// IsRevoked checks if a token identifier has been revoked.
+func (mrs *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	mrs.RLock()
+	defer mrs.RUnlock()
+	_, exists := mrs.revoked[jti]
+	return exists, nil
+}
+
+// Prune removes the entries of the tokens that have expired at a given time.
+func (mrs *MemoryRevocationStore) Prune(now time.Time) error {
+	mrs.Lock()
+	defer mrs.Unlock()
+	mrs.prune(now)
+	return nil
+}
+
+// prune removes the expired entries. The caller must hold the lock.
+func (mrs *MemoryRevocationStore) prune(now time.Time) {
+	for jti, expiresAt := range mrs.revoked {
+		if !expiresAt.IsZero() && now.After(expiresAt) {
+			delete(mrs.revoked, jti)
+		}
+	}
+}
+
+// FileRevocationStore is a RevocationStore that persists the revoked identifiers in a JSON file, so that
+// revocations survive the restarts of the service. The file is rewritten atomically on each change.
+type FileRevocationStore struct {
+	*MemoryRevocationStore
+	// path of the file.
+	path string
+}
+
+// NewFileRevocationStore creates a revocation store backed by a file, loading the entries already stored
+// in it. The file is created on the first revocation if it does not exist.
+func NewFileRevocationStore(path string) (*FileRevocationStore, error) {
+	return NewFileRevocationStoreWithClock(path, SystemClock)
+}
+
+// NewFileRevocationStoreWithClock creates a revocation store backed by a file that prunes the expired entries
+// attending to the time of the given clock.
+func NewFileRevocationStoreWithClock(path string, clock Clock) (*FileRevocationStore, error) {
+	store := &FileRevocationStore{
+		MemoryRevocationStore: NewMemoryRevocationStoreWithClock(clock),
+		path:                  path,
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, nerrors.NewInternalErrorFrom(err, "unable to read revocation file")
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &store.revoked); err != nil {
+			return nil, nerrors.NewInternalErrorFrom(err, "unable to parse revocation file")
+		}
+	}
+	return store, nil
+}
+
+// Revoke a token until its expiration time, persisting the change.
+func (frs *FileRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nerrors.NewInvalidArgumentError("token identifier cannot be empty")
+	}
+	frs.Lock()
+	defer frs.Unlock()
+	frs.revoked[jti] = expiresAt
+	frs.prune(frs.clock.Now())
+	return frs.persist()
+}
+
+// Prune removes the entries of the tokens that have expired at a given time, persisting the change.
+func (frs *FileRevocationStore) Prune(now time.Time) error {
+	frs.Lock()
+	defer frs.Unlock()
+	frs.prune(now)
+	return frs.persist()
+}
+
+// persist writes the entries in a temporal file and renames it to replace the previous one. The caller
+// must hold the lock.
+func (frs *FileRevocationStore) persist() error {
+	content, err := json.Marshal(frs.revoked)
+	if err != nil {
+		return nerrors.NewInternalErrorFrom(err, "unable to serialize revocations")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(frs.path), filepath.Base(frs.path)+".tmp")
+	if err != nil {
+		return nerrors.NewInternalErrorFrom(err, "unable to create temporal revocation file")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return nerrors.NewInternalErrorFrom(err, "unable to write revocation file")
+	}
+	if err := tmp.Close(); err != nil {
+		return nerrors.NewInternalErrorFrom(err, "unable to write revocation file")
+	}
+	if err := os.Rename(tmp.Name(), frs.path); err != nil {
+		return nerrors.NewInternalErrorFrom(err, "unable to replace revocation file")
+	}
+	return nil
+}
+
+// RevokeClaim adds the identifier of a claim to a revocation store until the claim expires.
+func RevokeClaim(store RevocationStore, claim *Claim) error {
+	if claim.Id == "" {
+		return nerrors.NewInvalidArgumentError("claim does not contain an identifier (jti)")
+	}
+	expiresAt := time.Time{}
+	if claim.ExpiresAt != 0 {
+		expiresAt = time.Unix(claim.ExpiresAt, 0)
+	}
+	return store.Revoke(claim.Id, expiresAt)
+}
@@ -0,0 +1,90 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("njwt revocation store tests", func() {
+	tokenMgr := New()
+	secret := "secret"
+
+	ginkgo.It("should reject revoked tokens", func() {
+		store := NewMemoryRevocationStore()
+		claim := NewClaim("tt", time.Hour, nil)
+		token, err := tokenMgr.Generate(claim, secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		opts := &ValidationOptions{Revocations: store}
+		_, err = tokenMgr.Recover(*token, secret, nil, opts)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		gomega.Expect(RevokeClaim(store, claim)).To(gomega.Succeed())
+		_, err = tokenMgr.Recover(*token, secret, nil, opts)
+		expectValidationError(err, jwt.ValidationErrorId)
+	})
+
+	ginkgo.It("should prune the entries of expired tokens", func() {
+		store := NewMemoryRevocationStore()
+		gomega.Expect(store.Revoke("expired", time.Now().Add(-time.Minute))).To(gomega.Succeed())
+		gomega.Expect(store.Revoke("valid", time.Now().Add(time.Hour))).To(gomega.Succeed())
+		gomega.Expect(store.Revoke("forever", time.Time{})).To(gomega.Succeed())
+
+		revoked, err := store.IsRevoked("expired")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(revoked).To(gomega.BeFalse())
+
+		gomega.Expect(store.Prune(time.Now().Add(2 * time.Hour))).To(gomega.Succeed())
+		revoked, err = store.IsRevoked("valid")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(revoked).To(gomega.BeFalse())
+		revoked, err = store.IsRevoked("forever")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(revoked).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("should persist the revocations in a file", func() {
+		dir, err := os.MkdirTemp("", "revocations")
+		gomega.Expect(err).To(gomega.Succeed())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "revoked.json")
+
+		store, err := NewFileRevocationStore(path)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(store.Revoke("jti", time.Now().Add(time.Hour))).To(gomega.Succeed())
+
+		reloaded, err := NewFileRevocationStore(path)
+		gomega.Expect(err).To(gomega.Succeed())
+		revoked, err := reloaded.IsRevoked("jti")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(revoked).To(gomega.BeTrue())
+
+		gomega.Expect(reloaded.Prune(time.Now().Add(2 * time.Hour))).To(gomega.Succeed())
+		reloaded, err = NewFileRevocationStore(path)
+		gomega.Expect(err).To(gomega.Succeed())
+		revoked, err = reloaded.IsRevoked("jti")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(revoked).To(gomega.BeFalse())
+	})
+})
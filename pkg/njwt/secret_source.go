@@ -0,0 +1,75 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+)
+
+// SecretSource defines the methods required to obtain the secrets of the tokens signed with HMAC when they
+// can change at runtime, for example, when they are reloaded from a file.
+type SecretSource interface {
+	// Secret returns the secret used to sign new tokens.
+	Secret() string
+	// Secrets returns the secrets accepted to verify tokens, starting with the current one.
+	Secrets() []string
+}
+
+// StaticSecretSource is a SecretSource with a secret that never changes.
+type StaticSecretSource string
+
+// Secret returns the secret used to sign new tokens.
+func (sss StaticSecretSource) Secret() string {
+	return string(sss)
+}
+
+// Secrets returns the secrets accepted to verify tokens.
+func (sss StaticSecretSource) Secrets() []string {
+	return []string{string(sss)}
+}
+
+// RecoverWithSecretSource recovers the claim from a token, verifying it with the secrets of the source in
+// order. The next secret is only tried if the signature of the token does not match the previous one.
+// Example:
+//
+//	token, err := tokenMgr.Generate(claim, source.Secret())
+//	recoveredClaim, err := RecoverWithSecretSource(tokenMgr, *token, source, &AuthxClaim{})
+func RecoverWithSecretSource(tokenMgr TokenManager, tk string, source SecretSource, pc interface{}, opts ...*ValidationOptions) (*Claim, error) {
+	secrets := source.Secrets()
+	if len(secrets) == 0 {
+		return nil, nerrors.NewFailedPreconditionError("secret source does not contain any secret")
+	}
+	var err error
+	for _, secret := range secrets {
+		var claim *Claim
+		claim, err = tokenMgr.Recover(tk, secret, pc, opts...)
+		if err == nil {
+			return claim, nil
+		}
+		if !isSignatureError(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// isSignatureError checks if an error has been caused by a signature that does not match the key.
+func isSignatureError(err error) bool {
+	validationErr, ok := err.(*jwt.ValidationError)
+	return ok && validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0
+}
@@ -0,0 +1,73 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// testSecretSource is a SecretSource with a fixed list of secrets.
+type testSecretSource []string
+
+func (tss testSecretSource) Secret() string {
+	return tss[0]
+}
+
+func (tss testSecretSource) Secrets() []string {
+	return tss
+}
+
+var _ = ginkgo.Describe("njwt secret source tests", func() {
+
+	tokenMgr := New()
+
+	ginkgo.It("should recover tokens signed with any of the secrets", func() {
+		source := testSecretSource{"new", "old"}
+		for _, secret := range source {
+			token, err := tokenMgr.Generate(NewClaim("tt", time.Hour, GenerateTestAuthxClaim()), secret)
+			gomega.Expect(err).Should(gomega.Succeed())
+			claim, err := RecoverWithSecretSource(tokenMgr, *token, source, &AuthxClaim{})
+			gomega.Expect(err).Should(gomega.Succeed())
+			gomega.Expect(claim.GetAuthxClaim()).ShouldNot(gomega.BeNil())
+		}
+	})
+
+	ginkgo.It("should reject tokens signed with an unknown secret", func() {
+		token, err := tokenMgr.Generate(NewClaim("tt", time.Hour, GenerateTestAuthxClaim()), "other")
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = RecoverWithSecretSource(tokenMgr, *token, testSecretSource{"new", "old"}, &AuthxClaim{})
+		expectValidationError(err, jwt.ValidationErrorSignatureInvalid)
+	})
+
+	ginkgo.It("should not try other secrets if the claims are not valid", func() {
+		token, err := tokenMgr.Generate(NewClaim("tt", -time.Hour, GenerateTestAuthxClaim()), "new")
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = RecoverWithSecretSource(tokenMgr, *token, testSecretSource{"new", "old"}, &AuthxClaim{})
+		expectValidationError(err, jwt.ValidationErrorExpired)
+	})
+
+	ginkgo.It("should recover tokens with a static secret source", func() {
+		token, err := tokenMgr.Generate(NewClaim("tt", time.Hour, GenerateTestAuthxClaim()), "secret")
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = RecoverWithSecretSource(tokenMgr, *token, StaticSecretSource("secret"), &AuthxClaim{})
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+})
@@ -0,0 +1,262 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/rs/zerolog/log"
+)
+
+// RefreshTokenAudience is the audience of the refresh tokens. The interceptors always reject the tokens with this
+// audience, so that refresh tokens cannot be used in place of the access tokens.
+const RefreshTokenAudience = "refresh"
+
+// SessionConfig with the settings of the tokens issued by the SessionManager.
+type SessionConfig struct {
+	// Issuer of the tokens.
+	Issuer string
+	// Audience of the access tokens. If empty, the audience is not set.
+	Audience string
+	// AccessExpiration with the lifetime of the access tokens.
+	AccessExpiration time.Duration
+	// RefreshExpiration with the lifetime of the refresh tokens.
+	RefreshExpiration time.Duration
+	// Clock with the current time used to issue and validate the tokens. If nil, the system clock is used.
+	Clock Clock
+}
+
+// IsValid checks that the configuration is valid.
+func (sc *SessionConfig) IsValid() error {
+	if sc.AccessExpiration <= 0 {
+		return nerrors.NewFailedPreconditionError("access expiration must be positive")
+	}
+	if sc.RefreshExpiration < sc.AccessExpiration {
+		return nerrors.NewFailedPreconditionError("refresh expiration must not be shorter than access expiration")
+	}
+	return nil
+}
+
+// TokenPair with an access token and its linked refresh token.
+type TokenPair struct {
+	// AccessToken with the signed access token.
+	AccessToken string
+	// AccessClaim with the claim of the access token.
+	AccessClaim *Claim
+	// RefreshToken with the signed refresh token.
+	RefreshToken string
+	// RefreshClaim with the claim of the refresh token.
+	RefreshClaim *Claim
+}
+
+// tokenFamily with the tokens issued from the same login.
+type tokenFamily struct {
+	// current with the identifier of the only refresh token of the family that can be exchanged.
+	current string
+	// authxClaim with the information of the last access token, used if no new information is provided.
+	authxClaim *AuthxClaim
+	// expiresAt with the expiration of the current refresh token.
+	expiresAt time.Time
+	// tokens with the identifiers of the tokens of the family that must be revoked if the family is compromised,
+	// and their expiration. The exchanged refresh tokens and the expired tokens are dropped, as they are already
+	// rejected.
+	tokens map[string]time.Time
+}
+
+// SessionManager issues pairs of access and refresh tokens, and exchanges refresh tokens for new pairs. Refresh
+// tokens can only be used once: presenting a refresh token that has already been exchanged is considered a
+// reuse of a stolen token, and all the tokens of its family are revoked. The state of the families is kept in
+// memory, so the refresh tokens issued before a restart are rejected.
+type SessionManager struct {
+	sync.Mutex
+	// tokenMgr with the manager that signs and verifies the tokens.
+	tokenMgr KeyedTokenManager
+	// revocations with the store in which the tokens of compromised families are revoked.
+	revocations RevocationStore
+	// config with the settings of the issued tokens.
+	config SessionConfig
+	// families maps family identifiers to their state.
+	families map[string]*tokenFamily
+}
+
+// NewSessionManager creates a SessionManager. The revocation store must be shared with the services that
+// verify the access tokens so that the tokens of a compromised family are rejected.
+// Example:
+//
+//	sessionMgr, err := NewSessionManager(tokenMgr, revocations, SessionConfig{Issuer: "authx", AccessExpiration: 15 * time.Minute, RefreshExpiration: 24 * time.Hour})
+//	pair, err := sessionMgr.Issue(authxClaim)
+//	...
+//	pair, err = sessionMgr.Refresh(pair.RefreshToken, nil)
+func NewSessionManager(tokenMgr KeyedTokenManager, revocations RevocationStore, config SessionConfig) (*SessionManager, error) {
+	if tokenMgr == nil {
+		return nil, nerrors.NewInvalidArgumentError("token manager must be provided")
+	}
+	if revocations == nil {
+		return nil, nerrors.NewInvalidArgumentError("revocation store must be provided")
+	}
+	if err := config.IsValid(); err != nil {
+		return nil, err
+	}
+	return &SessionManager{
+		tokenMgr:    tokenMgr,
+		revocations: revocations,
+		config:      config,
+		families:    make(map[string]*tokenFamily, 0),
+	}, nil
+}
+
+// Issue a new pair of tokens for a user, starting a new family.
+func (sm *SessionManager) Issue(authxClaim *AuthxClaim) (*TokenPair, error) {
+	if authxClaim == nil || authxClaim.UserID == "" {
+		return nil, nerrors.NewInvalidArgumentError("authx claim with a user identifier must be provided")
+	}
+	sm.Lock()
+	defer sm.Unlock()
+	sm.prune(getClock(sm.config.Clock).Now())
+	family := &tokenFamily{tokens: make(map[string]time.Time, 0)}
+	return sm.issue(generateUUID(), family, authxClaim)
+}
+
+// Refresh exchanges a refresh token for a new pair of tokens. The information of the new access token is
+// taken from authxClaim, or from the previous access token if nil. The exchanged refresh token can not be used
+// again.
+func (sm *SessionManager) Refresh(refreshToken string, authxClaim *AuthxClaim) (*TokenPair, error) {
+	refreshClaim, err := sm.recoverRefreshClaim(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	pc := refreshClaim.PersonalClaim.(*RefreshClaim)
+
+	sm.Lock()
+	defer sm.Unlock()
+	sm.prune(getClock(sm.config.Clock).Now())
+	family, exists := sm.families[pc.FamilyID]
+	if !exists {
+		return nil, nerrors.NewUnauthenticatedError("refresh token is no longer valid")
+	}
+	if family.current != refreshClaim.Id {
+		log.Warn().Str("user_id", pc.UserID).Str("family_id", pc.FamilyID).Msg("refresh token reused, revoking the token family")
+		if err := sm.revokeFamily(pc.FamilyID, family); err != nil {
+			return nil, err
+		}
+		return nil, nerrors.NewUnauthenticatedError("refresh token has already been used")
+	}
+	if authxClaim == nil {
+		authxClaim = family.authxClaim
+	}
+	if authxClaim.UserID != pc.UserID {
+		return nil, nerrors.NewInvalidArgumentError("authx claim does not belong to the user of the refresh token")
+	}
+	return sm.issue(pc.FamilyID, family, authxClaim)
+}
+
+// Revoke all the tokens of the family of a refresh token, for example, when the user logs out.
+func (sm *SessionManager) Revoke(refreshToken string) error {
+	refreshClaim, err := sm.recoverRefreshClaim(refreshToken)
+	if err != nil {
+		return err
+	}
+	pc := refreshClaim.PersonalClaim.(*RefreshClaim)
+	sm.Lock()
+	defer sm.Unlock()
+	family, exists := sm.families[pc.FamilyID]
+	if !exists {
+		return nil
+	}
+	return sm.revokeFamily(pc.FamilyID, family)
+}
+
+// recoverRefreshClaim verifies a refresh token and returns its claim.
+func (sm *SessionManager) recoverRefreshClaim(refreshToken string) (*Claim, error) {
+	opts := &ValidationOptions{Audiences: []string{RefreshTokenAudience}, Clock: sm.config.Clock}
+	if sm.config.Issuer != "" {
+		opts.Issuers = []string{sm.config.Issuer}
+	}
+	claim, err := sm.tokenMgr.Recover(refreshToken, &RefreshClaim{}, opts)
+	if err != nil {
+		return nil, nerrors.NewUnauthenticatedErrorFrom(err, "invalid refresh token")
+	}
+	pc, ok := claim.PersonalClaim.(*RefreshClaim)
+	if !ok || pc.FamilyID == "" || pc.TokenID == "" {
+		return nil, nerrors.NewUnauthenticatedError("invalid refresh token")
+	}
+	return claim, nil
+}
+
+// issue a new pair of tokens in a family, making the new refresh token the current one. The caller must hold
+// the lock.
+func (sm *SessionManager) issue(familyID string, family *tokenFamily, authxClaim *AuthxClaim) (*TokenPair, error) {
+	accessClaim := NewClaimWithClock(sm.config.Clock, sm.config.Issuer, sm.config.AccessExpiration, authxClaim)
+	if sm.config.Audience != "" {
+		accessClaim.WithAudience(sm.config.Audience)
+	}
+	accessToken, err := sm.tokenMgr.Generate(accessClaim)
+	if err != nil {
+		return nil, err
+	}
+	refreshClaim := NewClaimWithClock(sm.config.Clock, sm.config.Issuer, sm.config.RefreshExpiration,
+		&RefreshClaim{UserID: authxClaim.UserID, TokenID: accessClaim.Id, FamilyID: familyID}).WithAudience(RefreshTokenAudience)
+	refreshToken, err := sm.tokenMgr.Generate(refreshClaim)
+	if err != nil {
+		return nil, err
+	}
+
+	// The reuse of the exchanged refresh token is detected as it is no longer the current one.
+	delete(family.tokens, family.current)
+	now := getClock(sm.config.Clock).Now()
+	for jti, expiresAt := range family.tokens {
+		if now.After(expiresAt) {
+			delete(family.tokens, jti)
+		}
+	}
+	family.current = refreshClaim.Id
+	family.authxClaim = authxClaim
+	family.expiresAt = time.Unix(refreshClaim.ExpiresAt, 0)
+	family.tokens[accessClaim.Id] = time.Unix(accessClaim.ExpiresAt, 0)
+	family.tokens[refreshClaim.Id] = family.expiresAt
+	sm.families[familyID] = family
+
+	return &TokenPair{
+		AccessToken:  *accessToken,
+		AccessClaim:  accessClaim,
+		RefreshToken: *refreshToken,
+		RefreshClaim: refreshClaim,
+	}, nil
+}
+
+// revokeFamily adds all the tokens of a family to the revocation store and forgets the family. The caller
+// must hold the lock.
+func (sm *SessionManager) revokeFamily(familyID string, family *tokenFamily) error {
+	delete(sm.families, familyID)
+	for jti, expiresAt := range family.tokens {
+		if err := sm.revocations.Revoke(jti, expiresAt); err != nil {
+			return nerrors.NewInternalErrorFrom(err, "unable to revoke the token family")
+		}
+	}
+	return nil
+}
+
+// prune forgets the families whose current refresh token has expired. The caller must hold the lock.
+func (sm *SessionManager) prune(now time.Time) {
+	for familyID, family := range sm.families {
+		if now.After(family.expiresAt) {
+			delete(sm.families, familyID)
+		}
+	}
+}
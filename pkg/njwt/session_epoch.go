@@ -0,0 +1,82 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+)
+
+// SessionEpochStore defines the methods required to maintain the session epoch of the users. The tokens of
+// a user issued before its epoch are rejected, which invalidates all the sessions of the user at once (logout
+// everywhere) without listing the identifiers of the tokens.
+type SessionEpochStore interface {
+	// SetEpoch sets the time before which the tokens of a user are rejected.
+	SetEpoch(userID string, epoch time.Time) error
+	// Epoch returns the session epoch of a user, or the zero time if it has not been set.
+	Epoch(userID string) (time.Time, error)
+}
+
+// MemorySessionEpochStore is a SessionEpochStore that keeps the epochs in memory.
+type MemorySessionEpochStore struct {
+	sync.RWMutex
+	// epochs maps user identifiers to their session epoch.
+	epochs map[string]time.Time
+}
+
+// NewMemorySessionEpochStore creates an empty in-memory session epoch store.
+func NewMemorySessionEpochStore() *MemorySessionEpochStore {
+	return &MemorySessionEpochStore{
+		epochs: make(map[string]time.Time, 0),
+	}
+}
+
+// SetEpoch sets the time before which the tokens of a user are rejected.
+func (mses *MemorySessionEpochStore) SetEpoch(userID string, epoch time.Time) error {
+	if userID == "" {
+		return nerrors.NewInvalidArgumentError("user identifier cannot be empty")
+	}
+	mses.Lock()
+	defer mses.Unlock()
+	mses.epochs[userID] = epoch
+	return nil
+}
+
+// Epoch returns the session epoch of a user, or the zero time if it has not been set.
+func (mses *MemorySessionEpochStore) Epoch(userID string) (time.Time, error) {
+	mses.RLock()
+	defer mses.RUnlock()
+	return mses.epochs[userID], nil
+}
+
+// CheckSessionEpoch checks that a token of a user, issued at the given Unix time, is later than the session
+// epoch of the user. As the iat claim has a precision of seconds, the tokens issued in the same second as the
+// epoch are rejected, as they may have been issued before it. The users must log in again once that second has
+// elapsed.
+func CheckSessionEpoch(store SessionEpochStore, userID string, issuedAt int64) error {
+	epoch, err := store.Epoch(userID)
+	if err != nil {
+		return nerrors.NewInternalErrorFrom(err, "unable to retrieve the session epoch of the user")
+	}
+	if !epoch.IsZero() && issuedAt <= epoch.Unix() {
+		return jwt.NewValidationError("token was issued before the session epoch of the user", jwt.ValidationErrorIssuedAt)
+	}
+	return nil
+}
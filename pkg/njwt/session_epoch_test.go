@@ -0,0 +1,42 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("njwt session epoch tests", func() {
+
+	ginkgo.It("should reject the tokens issued before the epoch of the user", func() {
+		store := NewMemorySessionEpochStore()
+		issuedAt := time.Now().Add(-time.Hour).Unix()
+		gomega.Expect(CheckSessionEpoch(store, "user", issuedAt)).To(gomega.Succeed())
+
+		epoch := time.Unix(time.Now().Unix(), 500*int64(time.Millisecond))
+		gomega.Expect(store.SetEpoch("user", epoch)).To(gomega.Succeed())
+		expectValidationError(CheckSessionEpoch(store, "user", issuedAt), jwt.ValidationErrorIssuedAt)
+		// The tokens issued in the same second as the epoch may have been issued before it.
+		expectValidationError(CheckSessionEpoch(store, "user", epoch.Unix()), jwt.ValidationErrorIssuedAt)
+		gomega.Expect(CheckSessionEpoch(store, "user", epoch.Unix()+1)).To(gomega.Succeed())
+		gomega.Expect(CheckSessionEpoch(store, "other", issuedAt)).To(gomega.Succeed())
+	})
+})
@@ -0,0 +1,130 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("njwt session manager tests", func() {
+
+	var tokenMgr KeyedTokenManager
+	var revocations *MemoryRevocationStore
+	var sessionMgr *SessionManager
+	config := SessionConfig{Issuer: "authx", Audience: "napptive", AccessExpiration: time.Minute, RefreshExpiration: time.Hour}
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		tokenMgr, err = NewWithKey(NewHMACKey("test", "secret"))
+		gomega.Expect(err).To(gomega.Succeed())
+		revocations = NewMemoryRevocationStore()
+		sessionMgr, err = NewSessionManager(tokenMgr, revocations, config)
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should issue linked access and refresh tokens", func() {
+		authxClaim := GenerateTestAuthxClaim()
+		pair, err := sessionMgr.Issue(authxClaim)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		access, err := tokenMgr.Recover(pair.AccessToken, &AuthxClaim{}, &ValidationOptions{Audiences: []string{"napptive"}})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(access.GetAuthxClaim().UserID).To(gomega.Equal(authxClaim.UserID))
+
+		refresh, err := tokenMgr.Recover(pair.RefreshToken, &RefreshClaim{})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(refresh.PersonalClaim.(*RefreshClaim).TokenID).To(gomega.Equal(access.Id))
+
+		// the refresh token is not accepted as an access token
+		_, err = tokenMgr.Recover(pair.RefreshToken, &AuthxClaim{}, &ValidationOptions{Audiences: []string{"napptive"}})
+		expectValidationError(err, jwt.ValidationErrorAudience)
+		_, err = sessionMgr.Refresh(pair.AccessToken, nil)
+		gomega.Expect(nerrors.FromError(err).Code).To(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should rotate the refresh token", func() {
+		authxClaim := GenerateTestAuthxClaim()
+		first, err := sessionMgr.Issue(authxClaim)
+		gomega.Expect(err).To(gomega.Succeed())
+		second, err := sessionMgr.Refresh(first.RefreshToken, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(second.RefreshClaim.Id).NotTo(gomega.Equal(first.RefreshClaim.Id))
+		gomega.Expect(second.AccessClaim.GetAuthxClaim().UserID).To(gomega.Equal(authxClaim.UserID))
+
+		third, err := sessionMgr.Refresh(second.RefreshToken, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(third.RefreshClaim.PersonalClaim.(*RefreshClaim).FamilyID).
+			To(gomega.Equal(first.RefreshClaim.PersonalClaim.(*RefreshClaim).FamilyID))
+	})
+
+	ginkgo.It("should revoke the family when a refresh token is reused", func() {
+		first, err := sessionMgr.Issue(GenerateTestAuthxClaim())
+		gomega.Expect(err).To(gomega.Succeed())
+		second, err := sessionMgr.Refresh(first.RefreshToken, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		_, err = sessionMgr.Refresh(first.RefreshToken, nil)
+		gomega.Expect(nerrors.FromError(err).Code).To(gomega.Equal(nerrors.Unauthenticated))
+
+		// the legitimate refresh token and the issued access tokens are no longer valid
+		_, err = sessionMgr.Refresh(second.RefreshToken, nil)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		opts := &ValidationOptions{Revocations: revocations}
+		_, err = tokenMgr.Recover(second.AccessToken, &AuthxClaim{}, opts)
+		expectValidationError(err, jwt.ValidationErrorId)
+		_, err = tokenMgr.Recover(first.AccessToken, &AuthxClaim{}, opts)
+		expectValidationError(err, jwt.ValidationErrorId)
+	})
+
+	ginkgo.It("should only keep the tokens of the family that can be revoked", func() {
+		clock := NewFakeClock(time.Unix(time.Now().Unix(), 0))
+		clockConfig := config
+		clockConfig.Clock = clock
+		keyring, err := NewMemoryKeyring(NewHMACKey("test", "secret"))
+		gomega.Expect(err).To(gomega.Succeed())
+		tokenMgr, err = NewWithKeyringAndClock(keyring, clock)
+		gomega.Expect(err).To(gomega.Succeed())
+		sessionMgr, err = NewSessionManager(tokenMgr, revocations, clockConfig)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		pair, err := sessionMgr.Issue(GenerateTestAuthxClaim())
+		gomega.Expect(err).To(gomega.Succeed())
+		familyID := pair.RefreshClaim.PersonalClaim.(*RefreshClaim).FamilyID
+		for i := 0; i < 10; i++ {
+			clock.Advance(25 * time.Second)
+			pair, err = sessionMgr.Refresh(pair.RefreshToken, nil)
+			gomega.Expect(err).To(gomega.Succeed())
+		}
+		// the current refresh token and the access tokens that have not expired yet
+		tokens := sessionMgr.families[familyID].tokens
+		gomega.Expect(tokens).To(gomega.HaveLen(4))
+		gomega.Expect(tokens).To(gomega.HaveKey(pair.RefreshClaim.Id))
+		gomega.Expect(tokens).To(gomega.HaveKey(pair.AccessClaim.Id))
+	})
+
+	ginkgo.It("should reject the refresh of another user", func() {
+		pair, err := sessionMgr.Issue(GenerateTestAuthxClaim())
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = sessionMgr.Refresh(pair.RefreshToken, GenerateTestAuthxClaim())
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
@@ -34,9 +34,11 @@ type TokenManager interface {
 	// Generate a new token with a claim.
 	Generate(claim *Claim, secret string) (*string, error)
 	// Recover the claim from a token, if you want to recover the personal claim you must include the appropriate object.
+	// Optionally, a set of validation options can be passed to check the standard claims.
 	// Example:
 	//   recoveredClaim, err := tokenMgr.Recover(*token, secret, &AuthxClaim{})
-	Recover(tk string, secret string, pc interface{}) (*Claim, error)
+	//   recoveredClaim, err := tokenMgr.Recover(*token, secret, &AuthxClaim{}, &ValidationOptions{Issuers: []string{"authx"}})
+	Recover(tk string, secret string, pc interface{}, opts ...*ValidationOptions) (*Claim, error)
 	// RecoverUnverified parses the token returning the parsed claim.
 	// NOTICE: This method does not verify the authenticity of the token as no secret is used to check it.
 	// This method is intended to be used by clients that need to check data from a token provided by the library
@@ -47,12 +49,29 @@ type TokenManager interface {
 	RecoverUnverified(tk string, pc interface{}) (*Claim, error)
 }
 
-// New create a new instance of the token generator.
+// New create a new instance of the token generator. The tokens are signed with a single secret and they do not
+// contain the kid header, use NewHMACKey and NewWithKeyring to rotate the secrets.
 func New() TokenManager {
-	return &manager{}
+	return &manager{clock: SystemClock}
 }
 
-type manager struct{}
+// NewWithClock creates a new instance of the token generator that validates the tokens with the time of the
+// given clock, unless the validation options set a different one.
+// Example:
+//
+//	clock := NewFakeClock(time.Now())
+//	tokenMgr := NewWithClock(clock)
+//	token, err := tokenMgr.Generate(NewClaimWithClock(clock, "tt", time.Hour, pc), secret)
+//	clock.Advance(2 * time.Hour)
+//	_, err = tokenMgr.Recover(*token, secret, &AuthxClaim{}) // expired
+func NewWithClock(clock Clock) TokenManager {
+	return &manager{clock: getClock(clock)}
+}
+
+type manager struct {
+	// clock with the current time used to validate the tokens.
+	clock Clock
+}
 
 // Generate a new token with a claim.
 func (*manager) Generate(claim *Claim, secret string) (*string, error) {
@@ -67,9 +86,8 @@ func (*manager) Generate(claim *Claim, secret string) (*string, error) {
 // Recover the claim from a token, if you want to recover the personal claim yo must include the appropiated object.
 // Example:
 //   recoveredClaim, err := tokenMgr.Recover(*token, secret, &AuthxClaim{})
-func (*manager) Recover(tk string, secret string, pc interface{}) (*Claim, error) {
-	claim := &Claim{PersonalClaim: pc}
-	_, err := jwt.ParseWithClaims(tk, claim, func(token *jwt.Token) (interface{}, error) {
+func (m *manager) Recover(tk string, secret string, pc interface{}, opts ...*ValidationOptions) (*Claim, error) {
+	return ParseWithKeyFunc(tk, pc, func(token *jwt.Token) (interface{}, error) {
 		// From https://github.com/golang-jwt/jwt security notice related to
 		// https://auth0.com/blog/critical-vulnerabilities-in-json-web-token-libraries/
 		// Don't forget to validate the alg is what you expect.
@@ -78,12 +96,7 @@ func (*manager) Recover(tk string, secret string, pc interface{}) (*Claim, error
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(secret), nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-	return claim, nil
+	}, withClock(m.clock, opts...))
 }
 
 // RecoverUnverified parses the token returning the parsed claim.
@@ -94,6 +107,11 @@ func (*manager) Recover(tk string, secret string, pc interface{}) (*Claim, error
 //   personalClaim := &AuthxClaim{}
 //	 unverifiedClaim, err := tokenMgr.RecoverUnverified(*token, personalClaim)
 func (*manager) RecoverUnverified(tk string, pc interface{}) (*Claim, error) {
+	return recoverUnverified(tk, pc)
+}
+
+// recoverUnverified parses the token without checking its signature.
+func recoverUnverified(tk string, pc interface{}) (*Claim, error) {
 	claim := &Claim{PersonalClaim: pc}
 	_, _, err := new(jwt.Parser).ParseUnverified(tk, claim)
 	if err != nil {
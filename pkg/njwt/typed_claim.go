@@ -0,0 +1,112 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+)
+
+// TypedClaim is a Claim whose personal claim has a known type, so that it can be accessed without
+// type assertions.
+// Example:
+//
+//	claim := NewTypedClaim("authx", time.Hour, authxClaim)
+//	token, err := Generate(tokenMgr, claim, secret)
+//	recovered, err := Recover[AuthxClaim](tokenMgr, *token, secret)
+//	userID := recovered.PersonalClaim.UserID
+type TypedClaim[T any] struct {
+	jwt.StandardClaims
+	// PersonalClaim contains the related information with the Napptive platform.
+	PersonalClaim *T `json:"pc,omitempty"`
+}
+
+// NewTypedClaim creates a new TypedClaim instance.
+func NewTypedClaim[T any](issuer string, expiration time.Duration, pc *T) *TypedClaim[T] {
+	return NewTypedClaimWithClock(SystemClock, issuer, expiration, pc)
+}
+
+// NewTypedClaimWithClock creates a new TypedClaim instance issued at the current time of the clock.
+func NewTypedClaimWithClock[T any](clock Clock, issuer string, expiration time.Duration, pc *T) *TypedClaim[T] {
+	return &TypedClaim[T]{
+		StandardClaims: NewClaimWithClock(clock, issuer, expiration, nil).StandardClaims,
+		PersonalClaim:  pc,
+	}
+}
+
+// ToClaim transforms the TypedClaim into a standard Claim.
+func (tc *TypedClaim[T]) ToClaim() *Claim {
+	claim := &Claim{StandardClaims: tc.StandardClaims}
+	if tc.PersonalClaim != nil {
+		claim.PersonalClaim = tc.PersonalClaim
+	}
+	return claim
+}
+
+// TypedClaimFrom transforms a standard Claim into a TypedClaim. It returns an error if the personal
+// claim is not of the expected type.
+func TypedClaimFrom[T any](claim *Claim) (*TypedClaim[T], error) {
+	result := &TypedClaim[T]{StandardClaims: claim.StandardClaims}
+	switch pc := claim.PersonalClaim.(type) {
+	case nil:
+	case *T:
+		result.PersonalClaim = pc
+	case T:
+		result.PersonalClaim = &pc
+	default:
+		return nil, nerrors.NewInvalidArgumentError("unexpected personal claim type %T", claim.PersonalClaim)
+	}
+	return result, nil
+}
+
+// Generate a new token with a TypedClaim.
+func Generate[T any](tokenMgr TokenManager, claim *TypedClaim[T], secret string) (*string, error) {
+	return tokenMgr.Generate(claim.ToClaim(), secret)
+}
+
+// Recover a TypedClaim from a token.
+// Example:
+//
+//	recovered, err := Recover[RefreshClaim](tokenMgr, *token, secret)
+func Recover[T any](tokenMgr TokenManager, tk string, secret string, opts ...*ValidationOptions) (*TypedClaim[T], error) {
+	claim, err := tokenMgr.Recover(tk, secret, new(T), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return TypedClaimFrom[T](claim)
+}
+
+// RecoverKeyed recovers a TypedClaim from a token using a KeyedTokenManager.
+func RecoverKeyed[T any](tokenMgr KeyedTokenManager, tk string, opts ...*ValidationOptions) (*TypedClaim[T], error) {
+	claim, err := tokenMgr.Recover(tk, new(T), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return TypedClaimFrom[T](claim)
+}
+
+// RecoverUnverified parses the token returning the parsed TypedClaim.
+// NOTICE: This method does not verify the authenticity of the token.
+func RecoverUnverified[T any](tk string) (*TypedClaim[T], error) {
+	claim, err := recoverUnverified(tk, new(T))
+	if err != nil {
+		return nil, err
+	}
+	return TypedClaimFrom[T](claim)
+}
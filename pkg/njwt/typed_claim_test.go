@@ -0,0 +1,78 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"time"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("njwt typed claim tests", func() {
+	tokenMgr := New()
+	secret := "secret"
+
+	ginkgo.It("should round-trip an AuthxClaim", func() {
+		pc := GenerateTestAuthxClaim()
+		token, err := Generate(tokenMgr, NewTypedClaim("tt", time.Hour, pc), secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		recovered, err := Recover[AuthxClaim](tokenMgr, *token, secret)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(recovered.PersonalClaim).To(gomega.Equal(pc))
+		gomega.Expect(recovered.Issuer).To(gomega.Equal("tt"))
+	})
+
+	ginkgo.It("should round-trip a RefreshClaim", func() {
+		pc := NewRefreshClaim("userID", "tokenID")
+		token, err := Generate(tokenMgr, NewTypedClaim("tt", time.Hour, pc), secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		recovered, err := Recover[RefreshClaim](tokenMgr, *token, secret)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(recovered.PersonalClaim).To(gomega.Equal(pc))
+
+		_, err = Recover[RefreshClaim](tokenMgr, *token, "otherSecret")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should issue typed claims at the time of the clock", func() {
+		clock := NewFakeClock(time.Now().Add(-2 * time.Hour))
+		token, err := Generate(tokenMgr, NewTypedClaimWithClock(clock, "tt", time.Hour, GenerateTestAuthxClaim()), secret)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = Recover[AuthxClaim](tokenMgr, *token, secret)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should parse an unverified SignupClaim", func() {
+		pc := NewSignupClaim("id", "username", "github")
+		token, err := Generate(tokenMgr, NewTypedClaim("tt", time.Hour, pc), secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		recovered, err := RecoverUnverified[SignupClaim](*token)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(recovered.PersonalClaim).To(gomega.Equal(pc))
+	})
+
+	ginkgo.It("should fail instead of panicking with unexpected personal claims", func() {
+		claim := NewClaim("tt", time.Hour, NewRefreshClaim("userID", "tokenID"))
+		gomega.Expect(claim.GetAuthxClaim()).To(gomega.BeNil())
+		_, err := TypedClaimFrom[AuthxClaim](claim)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
@@ -0,0 +1,150 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// ValidationOptions with the checks applied to the standard claims of a token once its signature has been
+// verified. The zero value checks exp, iat and nbf only if they are present, matching the default behavior
+// of the jwt library.
+type ValidationOptions struct {
+	// Issuers with the list of accepted issuers. If empty, the issuer is not checked.
+	Issuers []string
+	// Audiences with the list of accepted audiences. If empty, the audience is not checked.
+	Audiences []string
+	// Leeway with the clock skew tolerated when checking the exp, nbf and iat claims.
+	Leeway time.Duration
+	// MaxAge with the maximum time elapsed since the token was issued. If set, the iat claim is required.
+	MaxAge time.Duration
+	// RequireExpiration forces the token to contain the exp claim.
+	RequireExpiration bool
+	// RequireNotBefore forces the token to contain the nbf claim.
+	RequireNotBefore bool
+	// Revocations with the store of revoked token identifiers. If set, the tokens whose jti has been revoked
+	// are rejected.
+	Revocations RevocationStore
+	// Clock with the current time used to check the exp, nbf and iat claims. If nil, the system clock is used.
+	Clock Clock
+}
+
+// Validate the standard claims at a given time. It returns a *jwt.ValidationError with the flags of the failed
+// checks so that callers can differentiate, for example, expired tokens.
+func (vo *ValidationOptions) Validate(claim *Claim, now time.Time) error {
+	var flags uint32
+	var messages []string
+	fail := func(flag uint32, format string, args ...interface{}) {
+		flags |= flag
+		messages = append(messages, fmt.Sprintf(format, args...))
+	}
+
+	leeway := int64(vo.Leeway / time.Second)
+	current := now.Unix()
+
+	if claim.ExpiresAt == 0 {
+		if vo.RequireExpiration {
+			fail(jwt.ValidationErrorExpired, "token does not contain expiration (exp) field")
+		}
+	} else if current > claim.ExpiresAt+leeway {
+		fail(jwt.ValidationErrorExpired, "token is expired by %v", time.Unix(current, 0).Sub(time.Unix(claim.ExpiresAt, 0)))
+	}
+
+	if claim.NotBefore == 0 {
+		if vo.RequireNotBefore {
+			fail(jwt.ValidationErrorNotValidYet, "token does not contain not before (nbf) field")
+		}
+	} else if current+leeway < claim.NotBefore {
+		fail(jwt.ValidationErrorNotValidYet, "token is not valid yet")
+	}
+
+	if claim.IssuedAt == 0 {
+		if vo.MaxAge > 0 {
+			fail(jwt.ValidationErrorIssuedAt, "token does not contain issued at (iat) field")
+		}
+	} else {
+		if current+leeway < claim.IssuedAt {
+			fail(jwt.ValidationErrorIssuedAt, "token used before issued")
+		}
+		if vo.MaxAge > 0 && current > claim.IssuedAt+int64(vo.MaxAge/time.Second)+leeway {
+			fail(jwt.ValidationErrorIssuedAt, "token is older than the maximum age %v", vo.MaxAge)
+		}
+	}
+
+	if len(vo.Issuers) > 0 && !contains(vo.Issuers, claim.Issuer) {
+		fail(jwt.ValidationErrorIssuer, "token issuer [%s] is not accepted", claim.Issuer)
+	}
+	if len(vo.Audiences) > 0 && !contains(vo.Audiences, claim.Audience) {
+		fail(jwt.ValidationErrorAudience, "token audience [%s] is not accepted", claim.Audience)
+	}
+
+	if vo.Revocations != nil && claim.Id != "" {
+		revoked, err := vo.Revocations.IsRevoked(claim.Id)
+		if err != nil {
+			fail(jwt.ValidationErrorId, "unable to check if the token has been revoked: %s", err.Error())
+		} else if revoked {
+			fail(jwt.ValidationErrorId, "token has been revoked")
+		}
+	}
+
+	if flags == 0 {
+		return nil
+	}
+	return jwt.NewValidationError(messages[0], flags)
+}
+
+// getValidationOptions returns the first set of options, or the default options if none are provided.
+func getValidationOptions(opts ...*ValidationOptions) *ValidationOptions {
+	if len(opts) > 0 && opts[0] != nil {
+		return opts[0]
+	}
+	return &ValidationOptions{}
+}
+
+// withClock returns the validation options using the given clock, unless the options already set one.
+func withClock(clock Clock, opts ...*ValidationOptions) *ValidationOptions {
+	validation := *getValidationOptions(opts...)
+	if validation.Clock == nil {
+		validation.Clock = clock
+	}
+	return &validation
+}
+
+// ParseWithKeyFunc parses a token verifying its signature with the key returned by keyFunc, and validates its
+// standard claims with the given options. It is intended for components that select the verification key
+// dynamically, for example, attending to the zone that issued the token.
+func ParseWithKeyFunc(tk string, pc interface{}, keyFunc jwt.Keyfunc, opts ...*ValidationOptions) (*Claim, error) {
+	return parse(&jwt.Parser{}, tk, pc, keyFunc, opts...)
+}
+
+// parse a token with a given parser, verifying its signature and validating its claims.
+func parse(parser *jwt.Parser, tk string, pc interface{}, keyFunc jwt.Keyfunc, opts ...*ValidationOptions) (*Claim, error) {
+	claim := &Claim{PersonalClaim: pc}
+	// The standard claims are checked after verifying the signature to apply the validation options.
+	parser.SkipClaimsValidation = true
+	if _, err := parser.ParseWithClaims(tk, claim, keyFunc); err != nil {
+		return nil, err
+	}
+	validation := getValidationOptions(opts...)
+	if err := validation.Validate(claim, getClock(validation.Clock).Now()); err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
@@ -0,0 +1,110 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// expectValidationError checks that the error is a validation error with the given flag.
+func expectValidationError(err error, flag uint32) {
+	gomega.Expect(err).NotTo(gomega.Succeed())
+	validationErr, ok := err.(*jwt.ValidationError)
+	gomega.Expect(ok).To(gomega.BeTrue())
+	gomega.Expect(validationErr.Errors & flag).NotTo(gomega.BeZero())
+}
+
+var _ = ginkgo.Describe("njwt validation options tests", func() {
+	tokenMgr := New()
+	secret := "secret"
+
+	ginkgo.It("should check the issuer and the audience", func() {
+		claim := NewClaim("authx", time.Hour, nil).WithAudience("playground")
+		token, err := tokenMgr.Generate(claim, secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		valid := &ValidationOptions{Issuers: []string{"authx"}, Audiences: []string{"catalog", "playground"}}
+		_, err = tokenMgr.Recover(*token, secret, nil, valid)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		_, err = tokenMgr.Recover(*token, secret, nil, &ValidationOptions{Issuers: []string{"other"}})
+		expectValidationError(err, jwt.ValidationErrorIssuer)
+		_, err = tokenMgr.Recover(*token, secret, nil, &ValidationOptions{Audiences: []string{"catalog"}})
+		expectValidationError(err, jwt.ValidationErrorAudience)
+	})
+
+	ginkgo.It("should apply the leeway to expired tokens", func() {
+		claim := NewClaim("tt", -30*time.Second, nil)
+		token, err := tokenMgr.Generate(claim, secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		_, err = tokenMgr.Recover(*token, secret, nil)
+		expectValidationError(err, jwt.ValidationErrorExpired)
+		_, err = tokenMgr.Recover(*token, secret, nil, &ValidationOptions{Leeway: time.Minute})
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should apply the leeway to tokens that are not valid yet", func() {
+		claim := NewClaim("tt", time.Hour, nil)
+		claim.NotBefore = time.Now().Add(30 * time.Second).Unix()
+		token, err := tokenMgr.Generate(claim, secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		_, err = tokenMgr.Recover(*token, secret, nil)
+		expectValidationError(err, jwt.ValidationErrorNotValidYet)
+		_, err = tokenMgr.Recover(*token, secret, nil, &ValidationOptions{Leeway: time.Minute})
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should reject tokens older than the maximum age", func() {
+		claim := NewClaim("tt", time.Hour, nil)
+		claim.IssuedAt = time.Now().Add(-10 * time.Minute).Unix()
+		token, err := tokenMgr.Generate(claim, secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		_, err = tokenMgr.Recover(*token, secret, nil, &ValidationOptions{MaxAge: 5 * time.Minute})
+		expectValidationError(err, jwt.ValidationErrorIssuedAt)
+		_, err = tokenMgr.Recover(*token, secret, nil, &ValidationOptions{MaxAge: 15 * time.Minute})
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should require the exp and nbf claims if requested", func() {
+		claim := &Claim{StandardClaims: jwt.StandardClaims{Id: generateUUID(), IssuedAt: time.Now().Unix()}}
+		token, err := tokenMgr.Generate(claim, secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		_, err = tokenMgr.Recover(*token, secret, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = tokenMgr.Recover(*token, secret, nil, &ValidationOptions{RequireExpiration: true})
+		expectValidationError(err, jwt.ValidationErrorExpired)
+		_, err = tokenMgr.Recover(*token, secret, nil, &ValidationOptions{RequireNotBefore: true})
+		expectValidationError(err, jwt.ValidationErrorNotValidYet)
+	})
+
+	ginkgo.It("should not validate the claims of a token with an invalid signature", func() {
+		claim := NewClaim("tt", -time.Hour, nil)
+		token, err := tokenMgr.Generate(claim, secret)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		_, err = tokenMgr.Recover(*token, "otherSecret", nil)
+		expectValidationError(err, jwt.ValidationErrorSignatureInvalid)
+	})
+})
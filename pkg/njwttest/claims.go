@@ -0,0 +1,192 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwttest
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/xid"
+)
+
+const (
+	// DefaultIssuer with the issuer of the claims built in the tests.
+	DefaultIssuer = "njwttest"
+	// DefaultExpiration with the expiration of the claims built in the tests.
+	DefaultExpiration = time.Hour
+	// DefaultRole with the role of the user in the accounts built in the tests.
+	DefaultRole = "Admin"
+)
+
+// AuthxClaimBuilder builds the authx claims used in the tests. By default, the user is the admin of a single
+// account with random identifiers.
+// Example:
+//
+//	authxClaim := njwttest.NewAuthxClaim().WithUsername("john").WithRole("Member").Build()
+type AuthxClaimBuilder struct {
+	claim njwt.AuthxClaim
+}
+
+// NewAuthxClaim creates a builder of authx claims with random identifiers.
+func NewAuthxClaim() *AuthxClaimBuilder {
+	userID := xid.New().String()
+	return (&AuthxClaimBuilder{claim: njwt.AuthxClaim{
+		UserID:        userID,
+		Username:      "user-" + userID,
+		EnvironmentID: xid.New().String(),
+		ZoneID:        "zone_id",
+		ZoneURL:       "zone_url",
+	}}).WithAccounts(njwt.UserAccountClaim{
+		Id:   xid.New().String(),
+		Name: "account-" + userID,
+		Role: DefaultRole,
+	})
+}
+
+// WithUserID sets the identifier of the user.
+func (acb *AuthxClaimBuilder) WithUserID(userID string) *AuthxClaimBuilder {
+	acb.claim.UserID = userID
+	return acb
+}
+
+// WithUsername sets the name of the user.
+func (acb *AuthxClaimBuilder) WithUsername(username string) *AuthxClaimBuilder {
+	acb.claim.Username = username
+	return acb
+}
+
+// WithAccounts replaces the accounts of the user. The first one becomes the current account.
+func (acb *AuthxClaimBuilder) WithAccounts(accounts ...njwt.UserAccountClaim) *AuthxClaimBuilder {
+	acb.claim.Accounts = append([]njwt.UserAccountClaim{}, accounts...)
+	acb.claim.AccountID = ""
+	acb.claim.AccountName = ""
+	acb.claim.EnvironmentAccountID = ""
+	acb.claim.AccountAdmin = false
+	if len(accounts) > 0 {
+		acb.claim.AccountID = accounts[0].Id
+		acb.claim.AccountName = accounts[0].Name
+		acb.claim.EnvironmentAccountID = accounts[0].Id
+		acb.claim.AccountAdmin = accounts[0].Role == DefaultRole
+	}
+	return acb
+}
+
+// WithAccount adds an account to the user. If it is the first one, it becomes the current account.
+func (acb *AuthxClaimBuilder) WithAccount(accountID string, accountName string, role string) *AuthxClaimBuilder {
+	return acb.WithAccounts(append(acb.claim.Accounts, njwt.UserAccountClaim{Id: accountID, Name: accountName, Role: role})...)
+}
+
+// WithRole sets the role of the user in the current account.
+func (acb *AuthxClaimBuilder) WithRole(role string) *AuthxClaimBuilder {
+	if len(acb.claim.Accounts) > 0 {
+		acb.claim.Accounts[0].Role = role
+		acb.claim.AccountAdmin = role == DefaultRole
+	}
+	return acb
+}
+
+// WithEnvironment sets the identifier of the current environment.
+func (acb *AuthxClaimBuilder) WithEnvironment(environmentID string) *AuthxClaimBuilder {
+	acb.claim.EnvironmentID = environmentID
+	return acb
+}
+
+// WithZone sets the zone that issued the claim.
+func (acb *AuthxClaimBuilder) WithZone(zoneID string, zoneURL string) *AuthxClaimBuilder {
+	acb.claim.ZoneID = zoneID
+	acb.claim.ZoneURL = zoneURL
+	return acb
+}
+
+// Build returns a copy of the claim.
+func (acb *AuthxClaimBuilder) Build() *njwt.AuthxClaim {
+	claim := acb.claim
+	claim.Accounts = append([]njwt.UserAccountClaim{}, acb.claim.Accounts...)
+	return &claim
+}
+
+// ClaimBuilder builds the claims used in the tests. Unlike njwt.NewClaim, the times of the claim can be set to
+// create, for example, expired tokens.
+// Example:
+//
+//	claim := njwttest.NewClaim(authxClaim).IssuedAt(time.Now().Add(-2 * time.Hour)).Build()
+type ClaimBuilder struct {
+	pc         interface{}
+	id         string
+	issuer     string
+	audience   string
+	issuedAt   time.Time
+	expiration time.Duration
+}
+
+// NewClaim creates a builder of claims with the given personal claim, issued now and valid for the default
+// expiration.
+func NewClaim(pc interface{}) *ClaimBuilder {
+	return &ClaimBuilder{
+		pc:         pc,
+		id:         xid.New().String(),
+		issuer:     DefaultIssuer,
+		issuedAt:   time.Now(),
+		expiration: DefaultExpiration,
+	}
+}
+
+// WithID sets the identifier (jti) of the claim.
+func (cb *ClaimBuilder) WithID(id string) *ClaimBuilder {
+	cb.id = id
+	return cb
+}
+
+// WithIssuer sets the issuer of the claim.
+func (cb *ClaimBuilder) WithIssuer(issuer string) *ClaimBuilder {
+	cb.issuer = issuer
+	return cb
+}
+
+// WithAudience sets the audience of the claim.
+func (cb *ClaimBuilder) WithAudience(audience string) *ClaimBuilder {
+	cb.audience = audience
+	return cb
+}
+
+// IssuedAt sets the time in which the claim is issued. The claim is valid from that time.
+func (cb *ClaimBuilder) IssuedAt(issuedAt time.Time) *ClaimBuilder {
+	cb.issuedAt = issuedAt
+	return cb
+}
+
+// WithExpiration sets the time the claim is valid since it is issued.
+func (cb *ClaimBuilder) WithExpiration(expiration time.Duration) *ClaimBuilder {
+	cb.expiration = expiration
+	return cb
+}
+
+// Build returns the claim.
+func (cb *ClaimBuilder) Build() *njwt.Claim {
+	return &njwt.Claim{
+		StandardClaims: jwt.StandardClaims{
+			Id:        cb.id,
+			Issuer:    cb.issuer,
+			Audience:  cb.audience,
+			IssuedAt:  cb.issuedAt.Unix(),
+			NotBefore: cb.issuedAt.Unix(),
+			ExpiresAt: cb.issuedAt.Add(cb.expiration).Unix(),
+		},
+		PersonalClaim: cb.pc,
+	}
+}
@@ -0,0 +1,34 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package njwttest contains the helpers to test the services that use njwt: a builder of claims, a fake issuer
+// of tokens with a controllable clock and keys, an in-memory secret provider, and a gRPC server harness with the
+// interceptors already configured.
+package njwttest
+
+import (
+	"time"
+
+	"github.com/napptive/njwt/pkg/njwt"
+)
+
+// FakeClock is a clock whose time only changes when the test moves it.
+type FakeClock = njwt.FakeClock
+
+// NewFakeClock creates a clock stopped at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return njwt.NewFakeClock(now)
+}
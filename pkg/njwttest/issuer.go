@@ -0,0 +1,214 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwttest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/config"
+	"github.com/napptive/njwt/pkg/interceptors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/xid"
+)
+
+// DefaultHeader with the metadata field used to send the tokens in the tests.
+const DefaultHeader = "authorization"
+
+// IssuerOption configures a fake issuer.
+type IssuerOption func(*Issuer)
+
+// WithClock sets the clock used to issue the tokens.
+func WithClock(clock *FakeClock) IssuerOption {
+	return func(i *Issuer) {
+		i.clock = clock
+	}
+}
+
+// WithSecret sets the secret used to sign the tokens.
+func WithSecret(secret string) IssuerOption {
+	return func(i *Issuer) {
+		i.secret = secret
+	}
+}
+
+// WithIssuerName sets the issuer (iss) of the tokens.
+func WithIssuerName(name string) IssuerOption {
+	return func(i *Issuer) {
+		i.name = name
+	}
+}
+
+// WithExpiration sets the time the tokens are valid since they are issued.
+func WithExpiration(expiration time.Duration) IssuerOption {
+	return func(i *Issuer) {
+		i.expiration = expiration
+	}
+}
+
+// WithSigningKeys makes the issuer sign the tokens with generated ES256 keys instead of the secret. The keys
+// can be rotated with RotateKey.
+func WithSigningKeys() IssuerOption {
+	return func(i *Issuer) {
+		i.useKeys = true
+	}
+}
+
+// Issuer is a fake issuer of tokens. The tokens are signed with a random secret, or with generated keys, and
+// their times are taken from a fake clock, so that the tests control when they expire.
+// Example:
+//
+//	issuer, err := njwttest.NewIssuer()
+//	token, err := issuer.AccessToken(njwttest.NewAuthxClaim().Build())
+//	issuer.Clock().Advance(-2 * time.Hour)
+//	expiredToken, err := issuer.AccessToken(njwttest.NewAuthxClaim().Build())
+type Issuer struct {
+	sync.Mutex
+	clock      *FakeClock
+	secret     string
+	name       string
+	expiration time.Duration
+	useKeys    bool
+	// keyring with the signing keys. It is shared with the interceptors so that they see the rotations.
+	keyring *njwt.MemoryKeyring
+	// tokenMgr signs the tokens with the active key of the keyring.
+	tokenMgr njwt.KeyedTokenManager
+}
+
+// NewIssuer creates a fake issuer whose clock is stopped at the current time.
+func NewIssuer(opts ...IssuerOption) (*Issuer, error) {
+	issuer := &Issuer{
+		clock:      NewFakeClock(time.Now()),
+		secret:     "secret-" + xid.New().String(),
+		name:       DefaultIssuer,
+		expiration: DefaultExpiration,
+	}
+	for _, opt := range opts {
+		opt(issuer)
+	}
+	if issuer.useKeys {
+		if err := issuer.RotateKey(); err != nil {
+			return nil, err
+		}
+	}
+	return issuer, nil
+}
+
+// Clock returns the clock used to issue the tokens.
+func (i *Issuer) Clock() *FakeClock {
+	return i.clock
+}
+
+// Secret returns the secret used to sign the tokens.
+func (i *Issuer) Secret() string {
+	return i.secret
+}
+
+// Config returns the configuration of the interceptors that accept the tokens of the issuer.
+func (i *Issuer) Config() config.JWTConfig {
+	return config.NewJWTConfig(i.secret, DefaultHeader)
+}
+
+// Keyring returns the keys of the issuer, or nil if the tokens are signed with the secret. The same keyring is
+// returned on every call, so the interceptors created with it accept the keys added by RotateKey, and the keys
+// replaced by RotateKey remain valid to verify the tokens.
+func (i *Issuer) Keyring() njwt.Keyring {
+	i.Lock()
+	defer i.Unlock()
+	if i.keyring == nil {
+		return nil
+	}
+	return i.keyring
+}
+
+// InterceptorOptions returns the options of the interceptors required to verify the tokens of the issuer. The
+// tokens are validated with the clock of the issuer.
+func (i *Issuer) InterceptorOptions() []interceptors.Option {
+	opts := []interceptors.Option{interceptors.WithClock(i.clock)}
+	if keyring := i.Keyring(); keyring != nil {
+		opts = append(opts, interceptors.WithKeyring(keyring))
+	}
+	return opts
+}
+
+// RotateKey generates a new signing key. It is only available for the issuers created with WithSigningKeys.
+func (i *Issuer) RotateKey() error {
+	if !i.useKeys {
+		return nerrors.NewFailedPreconditionError("issuer signs the tokens with a secret")
+	}
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nerrors.NewInternalErrorFrom(err, "cannot generate key")
+	}
+	key, err := njwt.NewSigningKey(xid.New().String(), jwt.SigningMethodES256, privateKey)
+	if err != nil {
+		return err
+	}
+	i.Lock()
+	defer i.Unlock()
+	if i.keyring != nil {
+		return i.keyring.Rotate(key)
+	}
+	keyring, err := njwt.NewMemoryKeyring(key)
+	if err != nil {
+		return err
+	}
+	tokenMgr, err := njwt.NewWithKeyring(keyring)
+	if err != nil {
+		return err
+	}
+	i.keyring, i.tokenMgr = keyring, tokenMgr
+	return nil
+}
+
+// NewClaim returns a builder of claims issued at the current time of the clock.
+func (i *Issuer) NewClaim(pc interface{}) *ClaimBuilder {
+	return NewClaim(pc).WithIssuer(i.name).IssuedAt(i.clock.Now()).WithExpiration(i.expiration)
+}
+
+// Token signs a claim.
+func (i *Issuer) Token(claim *njwt.Claim) (string, error) {
+	i.Lock()
+	tokenMgr := i.tokenMgr
+	i.Unlock()
+	var token *string
+	var err error
+	if tokenMgr != nil {
+		token, err = tokenMgr.Generate(claim)
+	} else {
+		token, err = njwt.New().Generate(claim, i.secret)
+	}
+	if err != nil {
+		return "", nerrors.NewInternalErrorFrom(err, "cannot sign token")
+	}
+	return *token, nil
+}
+
+// AccessToken issues a token with the given authx claim at the current time of the clock.
+func (i *Issuer) AccessToken(authxClaim *njwt.AuthxClaim) (string, error) {
+	return i.Token(i.NewClaim(authxClaim).Build())
+}
+
+// ExpiredToken issues a token with the given authx claim that expired before the current time of the clock.
+func (i *Issuer) ExpiredToken(authxClaim *njwt.AuthxClaim) (string, error) {
+	return i.Token(i.NewClaim(authxClaim).IssuedAt(i.clock.Now().Add(-i.expiration - time.Minute)).Build())
+}
@@ -0,0 +1,205 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwttest
+
+import (
+	"context"
+	"time"
+
+	grpc_ping_go "github.com/napptive/grpc-ping-go"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+)
+
+var _ = ginkgo.Describe("Claim builders", func() {
+
+	ginkgo.It("should build authx claims with the current account", func() {
+		authxClaim := NewAuthxClaim().WithUsername("john").WithAccount("id", "other", "Member").WithRole("Member").Build()
+		gomega.Expect(authxClaim.Username).Should(gomega.Equal("john"))
+		gomega.Expect(authxClaim.Accounts).Should(gomega.HaveLen(2))
+		gomega.Expect(authxClaim.AccountAdmin).Should(gomega.BeFalse())
+		gomega.Expect(authxClaim.HasRole(authxClaim.AccountName, "Member")).Should(gomega.BeTrue())
+		gomega.Expect(authxClaim.HasRole("other", "Member")).Should(gomega.BeTrue())
+	})
+
+	ginkgo.It("should build claims with the given times", func() {
+		issuedAt := time.Now().Add(-2 * time.Hour)
+		claim := NewClaim(NewAuthxClaim().Build()).IssuedAt(issuedAt).WithExpiration(time.Hour).WithAudience("aud").Build()
+		gomega.Expect(claim.IssuedAt).Should(gomega.Equal(issuedAt.Unix()))
+		gomega.Expect(claim.ExpiresAt).Should(gomega.Equal(issuedAt.Add(time.Hour).Unix()))
+		gomega.Expect(claim.Audience).Should(gomega.Equal("aud"))
+		gomega.Expect(claim.Valid()).ShouldNot(gomega.Succeed())
+	})
+})
+
+var _ = ginkgo.Describe("Fake issuer", func() {
+
+	ginkgo.It("should issue tokens with the time of the clock", func() {
+		clock := NewFakeClock(time.Now().Add(-2 * time.Hour))
+		issuer, err := NewIssuer(WithClock(clock), WithExpiration(time.Hour))
+		gomega.Expect(err).Should(gomega.Succeed())
+
+		token, err := issuer.AccessToken(NewAuthxClaim().Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = njwt.New().Recover(token, issuer.Secret(), &njwt.AuthxClaim{})
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+
+		clock.Advance(2 * time.Hour)
+		token, err = issuer.AccessToken(NewAuthxClaim().Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = njwt.New().Recover(token, issuer.Secret(), &njwt.AuthxClaim{})
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+
+	ginkgo.It("should keep the rotated keys valid", func() {
+		issuer, err := NewIssuer(WithSigningKeys())
+		gomega.Expect(err).Should(gomega.Succeed())
+		token, err := issuer.AccessToken(NewAuthxClaim().Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(issuer.RotateKey()).To(gomega.Succeed())
+
+		tokenMgr, err := njwt.NewWithKeyring(issuer.Keyring())
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = tokenMgr.Recover(token, &njwt.AuthxClaim{})
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+
+	ginkgo.It("should share the rotations with the interceptors", func() {
+		issuer, err := NewIssuer(WithSigningKeys())
+		gomega.Expect(err).Should(gomega.Succeed())
+		keyring := issuer.Keyring()
+		gomega.Expect(issuer.RotateKey()).To(gomega.Succeed())
+		gomega.Expect(issuer.Keyring()).Should(gomega.BeIdenticalTo(keyring))
+
+		token, err := issuer.AccessToken(NewAuthxClaim().Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		tokenMgr, err := njwt.NewWithKeyring(keyring)
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = tokenMgr.Recover(token, &njwt.AuthxClaim{})
+		gomega.Expect(err).Should(gomega.Succeed())
+	})
+
+	ginkgo.It("should not rotate keys when signing with a secret", func() {
+		issuer, err := NewIssuer()
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(issuer.RotateKey()).ShouldNot(gomega.Succeed())
+		gomega.Expect(issuer.Keyring()).Should(gomega.BeNil())
+	})
+})
+
+var _ = ginkgo.Describe("Memory secret provider", func() {
+
+	ginkgo.It("should return the secrets set during the test", func() {
+		provider := NewMemorySecretProvider(map[string]string{"zone": "secret"})
+		secret, err := provider.GetZoneSecret("zone")
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(*secret).Should(gomega.Equal("secret"))
+
+		provider.DeleteSecret("zone")
+		_, err = provider.GetZoneSecret("zone")
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.NotFound))
+		gomega.Expect(provider.Calls("zone")).Should(gomega.Equal(2))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		provider.SetSecret("zone", "other")
+		_, err = provider.GetZoneSecretWithContext(ctx, "zone")
+		gomega.Expect(nerrors.FromError(err).Code).Should(gomega.Equal(nerrors.Canceled))
+	})
+})
+
+var _ = ginkgo.Describe("Server harness", func() {
+
+	ping := func(server *Server, token string) (*grpc_ping_go.PingResponse, error) {
+		client := grpc_ping_go.NewPingServiceClient(server.Conn())
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if token != "" {
+			ctx = server.Context(ctx, token)
+		}
+		return client.Ping(ctx, &grpc_ping_go.PingRequest{RequestNumber: 1})
+	}
+
+	ginkgo.It("should authenticate the calls with the tokens of the issuer", func() {
+		issuer, err := NewIssuer(WithSigningKeys())
+		gomega.Expect(err).Should(gomega.Succeed())
+		server, err := NewPingServer(issuer.Config(), issuer.InterceptorOptions()...)
+		gomega.Expect(err).Should(gomega.Succeed())
+		defer server.Close()
+
+		token, err := issuer.AccessToken(NewAuthxClaim().WithUsername("john").Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		response, err := ping(server, token)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(response.Data).Should(gomega.ContainSubstring("john"))
+
+		_, err = ping(server, "")
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+
+		expired, err := issuer.ExpiredToken(NewAuthxClaim().Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = ping(server, expired)
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+
+		issuer.Clock().Advance(2 * DefaultExpiration)
+		_, err = ping(server, token)
+		gomega.Expect(nerrors.FromGRPC(err).Code).Should(gomega.Equal(nerrors.Unauthenticated))
+	})
+
+	ginkgo.It("should accept the tokens signed after a rotation", func() {
+		issuer, err := NewIssuer(WithSigningKeys())
+		gomega.Expect(err).Should(gomega.Succeed())
+		server, err := NewPingServer(issuer.Config(), issuer.InterceptorOptions()...)
+		gomega.Expect(err).Should(gomega.Succeed())
+		defer server.Close()
+
+		oldToken, err := issuer.AccessToken(NewAuthxClaim().Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(issuer.RotateKey()).To(gomega.Succeed())
+		newToken, err := issuer.AccessToken(NewAuthxClaim().Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		for _, token := range []string{oldToken, newToken} {
+			_, err = ping(server, token)
+			gomega.Expect(err).Should(gomega.Succeed())
+		}
+	})
+
+	ginkgo.It("should authenticate the calls attending to the zone of the token", func() {
+		issuer, err := NewIssuer()
+		gomega.Expect(err).Should(gomega.Succeed())
+		provider := NewMemorySecretProvider(map[string]string{"zone": issuer.Secret()})
+		server, err := NewZoneAwareServer(issuer.Config(), provider, func(s *grpc.Server) {
+			grpc_ping_go.RegisterPingServiceServer(s, PingHandler{})
+		})
+		gomega.Expect(err).Should(gomega.Succeed())
+		defer server.Close()
+
+		token, err := issuer.AccessToken(NewAuthxClaim().WithZone("zone", "url").Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = ping(server, token)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(provider.Calls("zone")).Should(gomega.Equal(1))
+
+		token, err = issuer.AccessToken(NewAuthxClaim().WithZone("other", "url").Build())
+		gomega.Expect(err).Should(gomega.Succeed())
+		_, err = ping(server, token)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+	})
+})
@@ -0,0 +1,93 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwttest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/interceptors"
+)
+
+// MemorySecretProvider is an in-memory secret provider whose secrets can be changed during the tests. It also
+// counts the retrievals of each zone.
+type MemorySecretProvider struct {
+	sync.RWMutex
+	secrets map[string]string
+	calls   map[string]int
+}
+
+// NewMemorySecretProvider creates a provider with a copy of the given secrets. The secret of the tokens without
+// zone is stored with the empty identifier.
+func NewMemorySecretProvider(secrets map[string]string) *MemorySecretProvider {
+	provider := &MemorySecretProvider{
+		secrets: make(map[string]string, len(secrets)),
+		calls:   make(map[string]int),
+	}
+	for zoneID, secret := range secrets {
+		provider.secrets[zoneID] = secret
+	}
+	return provider
+}
+
+// SetSecret sets the secret of a zone.
+func (msp *MemorySecretProvider) SetSecret(zoneID string, secret string) {
+	msp.Lock()
+	defer msp.Unlock()
+	msp.secrets[zoneID] = secret
+}
+
+// DeleteSecret removes the secret of a zone.
+func (msp *MemorySecretProvider) DeleteSecret(zoneID string) {
+	msp.Lock()
+	defer msp.Unlock()
+	delete(msp.secrets, zoneID)
+}
+
+// Calls returns the number of times the secret of a zone has been retrieved.
+func (msp *MemorySecretProvider) Calls(zoneID string) int {
+	msp.RLock()
+	defer msp.RUnlock()
+	return msp.calls[zoneID]
+}
+
+// GetZoneSecret retrieves the signing secret associated with a zone.
+func (msp *MemorySecretProvider) GetZoneSecret(zoneID string) (*string, error) {
+	return msp.GetZoneSecretWithContext(context.Background(), zoneID)
+}
+
+// GetZoneSecretWithContext retrieves the signing secret associated with a zone.
+func (msp *MemorySecretProvider) GetZoneSecretWithContext(ctx context.Context, zoneID string) (*string, error) {
+	if err := ctx.Err(); err == context.DeadlineExceeded {
+		return nil, nerrors.NewDeadlineExceededErrorFrom(err, "deadline exceeded retrieving secret of zone [%s]", zoneID)
+	} else if err != nil {
+		return nil, nerrors.NewCanceledErrorFrom(err, "request cancelled retrieving secret of zone [%s]", zoneID)
+	}
+	msp.Lock()
+	defer msp.Unlock()
+	msp.calls[zoneID]++
+	secret, exists := msp.secrets[zoneID]
+	if !exists {
+		return nil, nerrors.NewNotFoundError("secret of zone [%s] not found", zoneID)
+	}
+	return &secret, nil
+}
+
+// MemorySecretProvider must implement the secret provider interfaces.
+var _ interceptors.SecretProvider = (*MemorySecretProvider)(nil)
+var _ interceptors.ContextSecretProvider = (*MemorySecretProvider)(nil)
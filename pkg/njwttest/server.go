@@ -0,0 +1,134 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package njwttest
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	grpc_ping_go "github.com/napptive/grpc-ping-go"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/config"
+	"github.com/napptive/njwt/pkg/interceptors"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// bufSize with the size of the buffer of the in-memory connections.
+const bufSize = 1024 * 1024
+
+// Server is a gRPC server listening on an in-memory connection, with the JWT interceptors already configured.
+// Example:
+//
+//	server, err := njwttest.NewServer(issuer.Config(), func(s *grpc.Server) {
+//		grpc_ping_go.RegisterPingServiceServer(s, handler)
+//	}, issuer.InterceptorOptions()...)
+//	defer server.Close()
+//	client := grpc_ping_go.NewPingServiceClient(server.Conn())
+//	response, err := client.Ping(server.Context(context.Background(), token), request)
+type Server struct {
+	config   config.JWTConfig
+	server   *grpc.Server
+	listener *bufconn.Listener
+	conn     *grpc.ClientConn
+}
+
+// NewServer creates a server with the unary and stream JWT interceptors. The register function adds the
+// services to the server before it starts serving.
+func NewServer(cfg config.JWTConfig, register func(s *grpc.Server), opts ...interceptors.Option) (*Server, error) {
+	return newServer(cfg, register,
+		interceptors.WithServerJWTInterceptor(cfg, opts...),
+		interceptors.WithServerJWTStreamInterceptor(cfg, opts...))
+}
+
+// NewZoneAwareServer creates a server with the unary and stream zone aware JWT interceptors.
+func NewZoneAwareServer(cfg config.JWTConfig, secretProvider interceptors.SecretProvider, register func(s *grpc.Server), opts ...interceptors.Option) (*Server, error) {
+	return newServer(cfg, register,
+		interceptors.WithZoneAwareJWTInterceptor(cfg, secretProvider, opts...),
+		interceptors.WithZoneAwareJWTStreamInterceptor(cfg, secretProvider, opts...))
+}
+
+// NewPingServer creates a server with the JWT interceptors and the PingHandler.
+func NewPingServer(cfg config.JWTConfig, opts ...interceptors.Option) (*Server, error) {
+	return NewServer(cfg, func(s *grpc.Server) {
+		grpc_ping_go.RegisterPingServiceServer(s, PingHandler{})
+	}, opts...)
+}
+
+// newServer starts serving and connects a client to the server.
+func newServer(cfg config.JWTConfig, register func(s *grpc.Server), serverOpts ...grpc.ServerOption) (*Server, error) {
+	listener := bufconn.Listen(bufSize)
+	server := grpc.NewServer(serverOpts...)
+	if register != nil {
+		register(server)
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			log.Error().Err(err).Msg("test server exited with error")
+		}
+	}()
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		server.Stop()
+		return nil, nerrors.NewInternalErrorFrom(err, "cannot connect to test server")
+	}
+	return &Server{
+		config:   cfg,
+		server:   server,
+		listener: listener,
+		conn:     conn,
+	}, nil
+}
+
+// Conn returns the client connection to the server.
+func (s *Server) Conn() *grpc.ClientConn {
+	return s.conn
+}
+
+// Context returns a new outgoing context that contains the token in the header of the configuration.
+func (s *Server) Context(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, s.config.Header, s.config.HeaderValue(token))
+}
+
+// Close stops the server and closes the client connection.
+func (s *Server) Close() {
+	_ = s.conn.Close()
+	s.server.Stop()
+	_ = s.listener.Close()
+}
+
+// PingHandler is a ping service that answers with the name of the authenticated user.
+type PingHandler struct{}
+
+// Ping returns the request number and the username of the verified claim.
+func (PingHandler) Ping(ctx context.Context, request *grpc_ping_go.PingRequest) (*grpc_ping_go.PingResponse, error) {
+	authxClaim, err := interceptors.AuthxClaimFromContext(ctx)
+	if err != nil {
+		return nil, nerrors.FromError(err).ToGRPC()
+	}
+	return &grpc_ping_go.PingResponse{
+		RequestNumber: request.RequestNumber,
+		Data:          fmt.Sprintf("Ping [%d] received from %s", request.RequestNumber, authxClaim.Username),
+	}, nil
+}
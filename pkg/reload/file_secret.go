@@ -0,0 +1,209 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package reload contains the secret sources that are reloaded when their origin changes.
+package reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/napptive/nerrors/pkg/nerrors"
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/rs/zerolog/log"
+)
+
+// previousSecret with a secret that has been replaced and the time until it is accepted.
+type previousSecret struct {
+	secret string
+	until  time.Time
+}
+
+// FileSecret is a njwt.SecretSource that reads the secret from a file and reloads it when the file changes.
+// The replaced secrets are still accepted to verify tokens during the overlap period, so that the tokens
+// signed before the rotation remain valid.
+type FileSecret struct {
+	sync.RWMutex
+	// path of the file that contains the secret.
+	path string
+	// overlap with the period of time the previous secrets are accepted.
+	overlap time.Duration
+	// current secret used to sign the tokens.
+	current string
+	// previous secrets accepted until the end of the overlap period.
+	previous []previousSecret
+	// clock with the current time used to compute the end of the overlap period.
+	clock njwt.Clock
+	// watcher notifying the changes of the directory that contains the file.
+	watcher *fsnotify.Watcher
+	// stop is closed to stop watching the file.
+	stop chan struct{}
+	// stopOnce ensures the watcher is only stopped once.
+	stopOnce sync.Once
+	// done is closed once the watch loop has finished.
+	done chan struct{}
+}
+
+// NewFileSecret creates a FileSecret reading the secret from the given file, and starts watching it until the
+// context is cancelled or the source is closed. The directory of the file is watched instead of the file
+// itself, so that the files mounted from Kubernetes secrets, which are replaced by swapping symbolic links,
+// are also reloaded.
+func NewFileSecret(ctx context.Context, path string, overlap time.Duration) (*FileSecret, error) {
+	return NewFileSecretWithClock(ctx, path, overlap, njwt.SystemClock)
+}
+
+// NewFileSecretWithClock creates a FileSecret like NewFileSecret that computes the end of the overlap period with
+// the given clock, so that the tests do not have to wait for the previous secrets to expire.
+func NewFileSecretWithClock(ctx context.Context, path string, overlap time.Duration, clock njwt.Clock) (*FileSecret, error) {
+	if clock == nil {
+		clock = njwt.SystemClock
+	}
+	if overlap < 0 {
+		return nil, nerrors.NewInvalidArgumentError("overlap period cannot be negative")
+	}
+	secret, err := readSecret(path)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nerrors.NewInternalErrorFrom(err, "cannot create file watcher")
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		_ = watcher.Close()
+		return nil, nerrors.NewInternalErrorFrom(err, "cannot watch secret file [%s]", path)
+	}
+	fs := &FileSecret{
+		path:    path,
+		overlap: overlap,
+		current: secret,
+		clock:   clock,
+		watcher: watcher,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go fs.watch(ctx)
+	return fs, nil
+}
+
+// readSecret reads the secret stored in a file, ignoring the surrounding whitespaces.
+func readSecret(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", nerrors.NewInternalErrorFrom(err, "cannot read secret file [%s]", path)
+	}
+	secret := strings.TrimSpace(string(content))
+	if secret == "" {
+		return "", nerrors.NewFailedPreconditionError("secret file [%s] is empty", path)
+	}
+	return secret, nil
+}
+
+// Secret returns the secret used to sign new tokens.
+func (fs *FileSecret) Secret() string {
+	fs.RLock()
+	defer fs.RUnlock()
+	return fs.current
+}
+
+// Secrets returns the secrets accepted to verify tokens, starting with the current one and followed by the
+// previous ones whose overlap period has not expired.
+func (fs *FileSecret) Secrets() []string {
+	fs.RLock()
+	defer fs.RUnlock()
+	now := fs.clock.Now()
+	secrets := []string{fs.current}
+	for _, previous := range fs.previous {
+		if now.Before(previous.until) {
+			secrets = append(secrets, previous.secret)
+		}
+	}
+	return secrets
+}
+
+// Reload reads the file again and swaps the current secret if it has changed. The replaced secret is kept
+// valid during the overlap period. If the file cannot be read, the current secret is kept.
+func (fs *FileSecret) Reload() error {
+	secret, err := readSecret(fs.path)
+	if err != nil {
+		return err
+	}
+	fs.Lock()
+	defer fs.Unlock()
+	if secret == fs.current {
+		return nil
+	}
+	now := fs.clock.Now()
+	previous := make([]previousSecret, 0, len(fs.previous)+1)
+	for _, p := range fs.previous {
+		if now.Before(p.until) && p.secret != secret {
+			previous = append(previous, p)
+		}
+	}
+	if fs.overlap > 0 {
+		previous = append([]previousSecret{{secret: fs.current, until: now.Add(fs.overlap)}}, previous...)
+	}
+	fs.current = secret
+	fs.previous = previous
+	log.Info().Str("path", fs.path).Msg("secret reloaded")
+	return nil
+}
+
+// watch reloads the secret when the directory of the file changes.
+func (fs *FileSecret) watch(ctx context.Context) {
+	defer close(fs.done)
+	defer fs.watcher.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-fs.stop:
+			return
+		case event, ok := <-fs.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			if err := fs.Reload(); err != nil {
+				log.Warn().Err(err).Str("path", fs.path).Msg("cannot reload secret, keeping the current one")
+			}
+		case err, ok := <-fs.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warn().Err(err).Str("path", fs.path).Msg("error watching secret file")
+		}
+	}
+}
+
+// Close stops watching the file. The last secret read remains available.
+func (fs *FileSecret) Close() error {
+	fs.stopOnce.Do(func() {
+		close(fs.stop)
+	})
+	<-fs.done
+	return nil
+}
+
+// FileSecret must implement njwt.SecretSource.
+var _ njwt.SecretSource = (*FileSecret)(nil)
@@ -0,0 +1,119 @@
+/**
+ * Copyright 2023 Napptive
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/napptive/njwt/pkg/njwt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("File secret", func() {
+
+	var dir string
+	var path string
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	writeSecret := func(secret string) {
+		tmp := filepath.Join(dir, ".secret.tmp")
+		gomega.Expect(os.WriteFile(tmp, []byte(secret+"\n"), 0600)).To(gomega.Succeed())
+		gomega.Expect(os.Rename(tmp, path)).To(gomega.Succeed())
+	}
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "reload")
+		gomega.Expect(err).Should(gomega.Succeed())
+		path = filepath.Join(dir, "secret")
+		writeSecret("first")
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	ginkgo.AfterEach(func() {
+		cancel()
+		gomega.Expect(os.RemoveAll(dir)).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should read the secret from the file", func() {
+		source, err := NewFileSecret(ctx, path, time.Minute)
+		gomega.Expect(err).Should(gomega.Succeed())
+		defer source.Close()
+		gomega.Expect(source.Secret()).Should(gomega.Equal("first"))
+		gomega.Expect(source.Secrets()).Should(gomega.Equal([]string{"first"}))
+	})
+
+	ginkgo.It("should reload the secret when the file changes and keep the previous one", func() {
+		source, err := NewFileSecret(ctx, path, time.Minute)
+		gomega.Expect(err).Should(gomega.Succeed())
+		defer source.Close()
+
+		writeSecret("second")
+		gomega.Eventually(source.Secret, 5*time.Second, 10*time.Millisecond).Should(gomega.Equal("second"))
+		gomega.Expect(source.Secrets()).Should(gomega.Equal([]string{"second", "first"}))
+	})
+
+	ginkgo.It("should discard the previous secret after the overlap period", func() {
+		clock := njwt.NewFakeClock(time.Now())
+		source, err := NewFileSecretWithClock(ctx, path, time.Minute, clock)
+		gomega.Expect(err).Should(gomega.Succeed())
+		defer source.Close()
+
+		writeSecret("second")
+		gomega.Expect(source.Reload()).To(gomega.Succeed())
+		gomega.Expect(source.Secrets()).Should(gomega.Equal([]string{"second", "first"}))
+		clock.Advance(59 * time.Second)
+		gomega.Expect(source.Secrets()).Should(gomega.Equal([]string{"second", "first"}))
+		clock.Advance(time.Second)
+		gomega.Expect(source.Secrets()).Should(gomega.Equal([]string{"second"}))
+	})
+
+	ginkgo.It("should keep the current secret if the file becomes invalid", func() {
+		source, err := NewFileSecret(ctx, path, time.Minute)
+		gomega.Expect(err).Should(gomega.Succeed())
+		defer source.Close()
+
+		writeSecret("")
+		gomega.Expect(source.Reload()).NotTo(gomega.Succeed())
+		gomega.Expect(source.Secret()).Should(gomega.Equal("first"))
+	})
+
+	ginkgo.It("should stop watching the file once closed", func() {
+		source, err := NewFileSecret(ctx, path, time.Minute)
+		gomega.Expect(err).Should(gomega.Succeed())
+		gomega.Expect(source.Close()).To(gomega.Succeed())
+		gomega.Expect(source.Close()).To(gomega.Succeed())
+
+		writeSecret("second")
+		gomega.Consistently(source.Secret, 200*time.Millisecond, 10*time.Millisecond).Should(gomega.Equal("first"))
+	})
+
+	ginkgo.It("should fail if the file does not exist", func() {
+		_, err := NewFileSecret(ctx, filepath.Join(dir, "missing"), time.Minute)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+	})
+
+	ginkgo.It("should fail with a negative overlap period", func() {
+		_, err := NewFileSecret(ctx, path, -time.Minute)
+		gomega.Expect(err).ShouldNot(gomega.Succeed())
+	})
+})
@@ -14,6 +14,8 @@
  * limitations under the License.
  */
 
+// Package utils contains random values used in the tests. The tests of other modules can use the builders of the
+// njwttest package instead.
 package utils
 
 import (
@@ -41,6 +43,7 @@ func GetTestUserName() string {
 	return faker.Internet().UserName()
 }
 
+// GetTestAccountName returns a random AccountName
 func GetTestAccountName() string {
 	return faker.Internet().UserName()
 }